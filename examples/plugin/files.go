@@ -4,6 +4,8 @@ Copyright © 2023 Mark Johnson
 package main
 
 import (
+	"errors"
+
 	"github.com/shelterbelt/majic-cli/examples/plugin/myplugin/helpers/myhelper"
 
 	"github.com/shelterbelt/majic-cli/majic/helpers/core"
@@ -17,20 +19,14 @@ var FilesCmd = &cobra.Command{
 	Aliases: []string{},
 	Short:   "change \"the\" to \"THE\" in text files",
 	Long:    ``,
-	Run: func(cmd *cobra.Command, args []string) {
-		core.Config().ApplyFlags(cmd.Flags())
-		appConfig := core.Config()
-		appConfig.ApplyFlags(cmd.Flags())
-
+	RunE: func(cmd *cobra.Command, args []string) error {
 		myProcessor := new(myhelper.MyFileProcessor)
 		myProcessor.Initialize(cmd.Flags())
 
-		if len(args) >= 1 {
-			path := args[0]
-			file.ProcessPath(path, myProcessor)
-		} else {
-			core.Output().NormalOutput("No file or directory to scan specified.")
+		if len(args) == 0 {
+			return &core.UsageError{Err: errors.New("no file or directory to scan specified")}
 		}
+		return file.ProcessPath(args[0], myProcessor)
 	},
 }
 
@@ -41,6 +37,7 @@ func init() {
 	// the CLI via the plugin's Register method.
 
 	// Here you will define your flags and configuration settings.
+	file.AddFlags(FilesCmd.Flags())
 
 	// Cobra supports Persistent Flags which will work for this command
 	// and all subcommands, e.g.:
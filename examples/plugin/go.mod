@@ -11,6 +11,12 @@ require (
 	github.com/spf13/cobra v1.9.1
 )
 
+require (
+	github.com/bmatcuk/doublestar/v4 v4.9.1 // indirect
+	github.com/pelletier/go-toml/v2 v2.2.4 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
 require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/magiconair/properties v1.8.10 // indirect
@@ -7,6 +7,7 @@ import (
 	"os"
 	"strings"
 
+	"github.com/shelterbelt/majic-cli/majic/helpers/file"
 	"github.com/spf13/pflag"
 )
 
@@ -17,6 +18,18 @@ type MyFileProcessor struct {
 func (processor *MyFileProcessor) Initialize(flags *pflag.FlagSet) {
 }
 
+// Clone allows files to be processed at the same time with
+// --jobs.
+func (processor *MyFileProcessor) Clone() file.FileProcessor {
+	return &MyFileProcessor{}
+}
+
+// SetRelativePath opts in to mirroring the input directory
+// structure in the output directory.  The output is named after
+// the input file, so the path itself isn't needed.
+func (processor *MyFileProcessor) SetRelativePath(relativePath string) {
+}
+
 func (process *MyFileProcessor) PreprocessNewTargetFile(file *os.File) {
 }
 
@@ -4,15 +4,42 @@ Copyright © 2023 Mark Johnson
 package main
 
 import (
+	"log/slog"
+
 	"github.com/shelterbelt/majic-cli/majic/helpers/core"
 )
 
+const PluginNamespace string = "myplugin"
+const ConfigKeyDefaultName string = "default_name"
+
 type myplugin struct {
 }
 
+// logger is replaced with one tagged with the plugin's name
+// when majic loads the plugin.
+var logger = core.Logger()
+
 func (plugin *myplugin) Register() []string {
-	core.Output().DetailedOutput("Registering majic CLI Sample plugin.")
+	logger.Info("registering majic CLI Sample plugin")
 	return []string{"SayHiCmd", "FilesCmd"}
 }
 
+// Optionally implement the LoggingPlugin interface to receive
+// a logger that tags messages with the plugin's name.
+func (plugin *myplugin) SetLogger(pluginLogger *slog.Logger) {
+	logger = pluginLogger
+}
+
+// Optionally implement the ConfigurablePlugin interface to
+// declare the configuration keys used by the plugin.
+func (plugin *myplugin) ConfigSchema() core.ConfigSchema {
+	return core.ConfigSchema{
+		Namespace:   PluginNamespace,
+		Description: "majic CLI Sample plugin",
+		Options: []core.ConfigOption{
+			{Key: ConfigKeyDefaultName, Type: core.ConfigTypeString, Default: "World", Description: "name greeted by sayhi when none is given"},
+		},
+	}
+}
+
 var Plugin myplugin
@@ -14,10 +14,12 @@ var SayHiCmd = &cobra.Command{
 	Aliases: []string{"hi"},
 	Short:   "sample CLI command that says 'hi'",
 	Long:    ``,
-	Run: func(cmd *cobra.Command, args []string) {
-		core.Config().ApplyFlags(cmd.Flags())
+	RunE: func(cmd *cobra.Command, args []string) error {
 
-		name := "World"
+		name, err := core.Config().GetString(core.QualifiedConfigKey(PluginNamespace, ConfigKeyDefaultName))
+		if err != nil {
+			return err
+		}
 		if len(args) >= 1 {
 			name = args[0]
 		}
@@ -28,6 +30,7 @@ var SayHiCmd = &cobra.Command{
 		} else {
 			core.Output().NormalOutput("Hello " + name + ".")
 		}
+		return nil
 	},
 }
 
@@ -4,23 +4,56 @@ Copyright © 2023 Mark Johnson
 package cmd
 
 import (
+	"io/fs"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/shelterbelt/majic-cli/majic/helpers/core"
 	"github.com/spf13/cobra"
 )
 
+// cleanResult is the result of the clean command.
+type cleanResult struct {
+	OutputDir string   `json:"output_dir" yaml:"output_dir"`
+	Deleted   []string `json:"deleted" yaml:"deleted"`
+}
+
+func (result cleanResult) String() string {
+	lines := []string{"Deleted contents of " + result.OutputDir}
+	for _, path := range result.Deleted {
+		lines = append(lines, "  "+path)
+	}
+	return strings.Join(lines, "\n")
+}
+
 // cleanCmd represents the clean command
 var cleanCmd = &cobra.Command{
 	Use:   "clean",
 	Short: "Permenantly delete temporary and generated output files created by the cli",
 	Long:  ``,
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		appConfig := core.Config()
-		appConfig.ApplyFlags(cmd.Flags())
-		outputPath := appConfig.GetD(core.ConfigKeyOutputDir, core.DefaultOutputDir).(string)
-		core.Output().NormalOutput("Deleting contents of " + outputPath)
-		os.RemoveAll(outputPath)
+		outputPath, err := appConfig.GetPath(core.ConfigKeyOutputDir)
+		if err != nil {
+			return err
+		}
+		core.Output().DetailedOutput("Deleting contents of " + outputPath)
+		result := cleanResult{OutputDir: outputPath, Deleted: []string{}}
+		err = filepath.WalkDir(outputPath, func(path string, entry fs.DirEntry, err error) error {
+			if err == nil && !entry.IsDir() {
+				result.Deleted = append(result.Deleted, path)
+			}
+			return err
+		})
+		if err != nil && !os.IsNotExist(err) {
+			return core.PathError(outputPath, err)
+		}
+		if err := os.RemoveAll(outputPath); err != nil {
+			return core.PathError(outputPath, err)
+		}
+		core.Output().Emit(result)
+		return nil
 	},
 }
 
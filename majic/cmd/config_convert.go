@@ -0,0 +1,65 @@
+/*
+Copyright © 2023 Mark Johnson
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/shelterbelt/majic-cli/majic/helpers/core"
+	"github.com/spf13/cobra"
+)
+
+const flagKeyTo string = "to"
+const convertBackupSuffix string = ".bak"
+
+// configConvertCmd represents the config convert command
+var configConvertCmd = &cobra.Command{
+	Use:   "convert --to yaml|toml|json|properties",
+	Short: "convert the configuration file to another format",
+	Long: `Rewrite the configuration file in another format, e.g. clirc to clirc.yaml.
+
+The previous file is kept with a .bak suffix.  Comments are only preserved
+when converting between properties files.`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		appConfig := core.Config()
+		to, _ := cmd.Flags().GetString(flagKeyTo)
+		format, err := core.ParseConfigFormat(to)
+		if err != nil {
+			return err
+		}
+		sourcePath := appConfig.FilePath()
+		targetPath := core.ConfigFilePathForFormat(sourcePath, format)
+		if targetPath == sourcePath {
+			core.Output().NormalOutput("Configuration file is already in " + string(format) + " format: " + sourcePath)
+			return nil
+		}
+		if _, err := os.Stat(targetPath); err == nil {
+			return fmt.Errorf("configuration file already exists: %s", targetPath)
+		}
+		err = appConfig.SaveAs(targetPath)
+		if err != nil {
+			return err
+		}
+		core.Output().StyledOutput(core.StyleSuccess, "Converted "+sourcePath+" to "+targetPath)
+		if _, err := os.Stat(sourcePath); err == nil {
+			err = os.Rename(sourcePath, sourcePath+convertBackupSuffix)
+			if err != nil {
+				return err
+			}
+			core.Output().NormalOutput("Previous configuration file kept as " + sourcePath + convertBackupSuffix)
+		}
+		return nil
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configConvertCmd)
+
+	// Here you will define your flags and configuration settings.
+	configConvertCmd.Flags().String(flagKeyTo, "", "target format: yaml, toml, json or properties")
+	configConvertCmd.MarkFlagRequired(flagKeyTo)
+}
@@ -0,0 +1,62 @@
+/*
+Copyright © 2023 Mark Johnson
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/shelterbelt/majic-cli/majic/helpers/core"
+	"github.com/spf13/cobra"
+)
+
+// configDescribeCmd represents the config describe command
+var configDescribeCmd = &cobra.Command{
+	Use:   "describe [namespace]",
+	Short: "document the configuration keys declared by majic and its plugins",
+	Long: `Print the configuration keys declared by majic and by any loaded plugins that
+publish a configuration schema, along with their types, defaults and help text.
+
+Use "majic" as the namespace to describe only the core settings.`,
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		described := false
+		for _, schema := range core.ConfigSchemas() {
+			if len(args) > 0 && args[0] != schema.Namespace {
+				continue
+			}
+			if described {
+				core.Output().NormalOutput("")
+			}
+			describeConfigSchema(schema)
+			described = true
+		}
+		if !described {
+			return fmt.Errorf("no configuration schema registered for namespace: %s", args[0])
+		}
+		return nil
+	},
+}
+
+func describeConfigSchema(schema core.ConfigSchema) {
+	core.Output().StyledOutput(core.StyleHeading, schema.Namespace+": "+schema.Description)
+	for _, option := range schema.Options {
+		key := core.QualifiedConfigKey(schema.Namespace, option.Key)
+		details := string(option.Type)
+		if len(option.Default) > 0 {
+			details += ", default: " + option.Default
+		}
+		if option.Secret {
+			details += ", secret"
+		}
+		core.Output().NormalOutput("  " + key + " (" + details + ")")
+		if len(option.Description) > 0 {
+			core.Output().StyledOutput(core.StyleDim, "      "+option.Description)
+		}
+	}
+}
+
+func init() {
+	configCmd.AddCommand(configDescribeCmd)
+}
@@ -0,0 +1,55 @@
+/*
+Copyright © 2023 Mark Johnson
+*/
+package cmd
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/shelterbelt/majic-cli/majic/helpers/core"
+	"github.com/spf13/cobra"
+)
+
+const defaultEditor string = "vi"
+
+// configEditCmd represents the config edit command
+var configEditCmd = &cobra.Command{
+	Use:          "edit",
+	Short:        "open the configuration file in $EDITOR",
+	Long:         ``,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	Annotations:  map[string]string{annotationAllowConfigErrors: "true"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		appConfig := core.Config()
+		if _, err := os.Stat(appConfig.FilePath()); errors.Is(err, os.ErrNotExist) {
+			if err := core.GenerateDefaultConfigFile(appConfig.FilePath(), false); err != nil {
+				return err
+			}
+		}
+		editor := strings.Fields(editorCommand())
+		editor = append(editor, appConfig.FilePath())
+		core.Output().DetailedOutput("Running: " + strings.Join(editor, " "))
+		editCmd := exec.Command(editor[0], editor[1:]...)
+		editCmd.Stdin = os.Stdin
+		editCmd.Stdout = os.Stdout
+		editCmd.Stderr = os.Stderr
+		return editCmd.Run()
+	},
+}
+
+func editorCommand() string {
+	for _, variable := range []string{"VISUAL", "EDITOR"} {
+		if editor := strings.TrimSpace(os.Getenv(variable)); len(editor) > 0 {
+			return editor
+		}
+	}
+	return defaultEditor
+}
+
+func init() {
+	configCmd.AddCommand(configEditCmd)
+}
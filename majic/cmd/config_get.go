@@ -0,0 +1,42 @@
+/*
+Copyright © 2023 Mark Johnson
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/shelterbelt/majic-cli/majic/helpers/core"
+	"github.com/spf13/cobra"
+)
+
+const flagKeyReveal string = "reveal"
+
+// configGetCmd represents the config get command
+var configGetCmd = &cobra.Command{
+	Use:          "get <key>",
+	Short:        "print the effective value of a configuration key",
+	Long:         ``,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		appConfig := core.Config()
+		value, _, found := appConfig.Lookup(args[0])
+		if !found {
+			return fmt.Errorf("configuration key not found: %s", args[0])
+		}
+		reveal, _ := cmd.Flags().GetBool(flagKeyReveal)
+		if !reveal {
+			value = core.RedactValue(args[0], value)
+		}
+		core.Output().NormalOutput(value)
+		return nil
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configGetCmd)
+
+	// Here you will define your flags and configuration settings.
+	configGetCmd.Flags().Bool(flagKeyReveal, false, "print secret values instead of redacting them")
+}
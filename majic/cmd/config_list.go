@@ -0,0 +1,41 @@
+/*
+Copyright © 2023 Mark Johnson
+*/
+package cmd
+
+import (
+	"github.com/shelterbelt/majic-cli/majic/helpers/core"
+	"github.com/spf13/cobra"
+)
+
+// configEntry is the result of the config list command for
+// a single key.
+type configEntry struct {
+	Key    string `json:"key" yaml:"key"`
+	Value  string `json:"value" yaml:"value"`
+	Source string `json:"source" yaml:"source"`
+}
+
+func (entry configEntry) String() string {
+	return entry.Key + " = " + entry.Value + " (" + entry.Source + ")"
+}
+
+// configListCmd represents the config list command
+var configListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "list the effective configuration and where each value came from",
+	Long:    ``,
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		appConfig := core.Config()
+		for _, key := range appConfig.Keys() {
+			core.Output().Emit(configEntry{key, appConfig.DisplayValue(key), appConfig.Source(key).String()})
+		}
+		return nil
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configListCmd)
+}
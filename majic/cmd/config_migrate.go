@@ -0,0 +1,58 @@
+/*
+Copyright © 2023 Mark Johnson
+*/
+package cmd
+
+import (
+	"strconv"
+
+	"github.com/shelterbelt/majic-cli/majic/helpers/core"
+	"github.com/spf13/cobra"
+)
+
+const flagKeyDryRun string = "dry-run"
+
+// configMigrateCmd represents the config migrate command
+var configMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "update the configuration file to the current configuration version",
+	Long: `Apply the configuration migrations registered by majic and its plugins that
+have not yet been applied to the configuration file.
+
+Until then majic applies them in memory each time it runs, without writing
+to the configuration file.  A backup of the previous file is written alongside
+it.  Use --dry-run to preview the changes without modifying anything.`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	Annotations:  map[string]string{annotationMigratesConfig: "true"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		appConfig := core.Config()
+		dryRun, _ := cmd.Flags().GetBool(flagKeyDryRun)
+		results, err := appConfig.Migrate(dryRun)
+		for _, result := range results {
+			migration := result.Migration
+			core.Output().StyledOutput(core.StyleHeading, migration.Namespace+" "+strconv.Itoa(result.FromVersion)+" -> "+strconv.Itoa(migration.Version)+": "+migration.Description)
+			for _, change := range result.Changes {
+				core.Output().NormalOutput("  " + change)
+			}
+		}
+		if err != nil {
+			return err
+		}
+		if len(results) == 0 {
+			core.Output().NormalOutput("Configuration file is up to date: " + appConfig.FilePath())
+		} else if dryRun {
+			core.Output().NormalOutput("Dry run, " + appConfig.FilePath() + " was not modified")
+		} else {
+			core.Output().StyledOutput(core.StyleSuccess, "Updated configuration file: "+appConfig.FilePath())
+		}
+		return nil
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configMigrateCmd)
+
+	// Here you will define your flags and configuration settings.
+	configMigrateCmd.Flags().Bool(flagKeyDryRun, false, "show the changes without modifying the configuration file")
+}
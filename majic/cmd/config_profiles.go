@@ -0,0 +1,51 @@
+/*
+Copyright © 2023 Mark Johnson
+*/
+package cmd
+
+import (
+	"github.com/shelterbelt/majic-cli/majic/helpers/core"
+	"github.com/spf13/cobra"
+)
+
+// profileEntry is the result of the config profiles command
+// for a single profile.
+type profileEntry struct {
+	Name   string `json:"name" yaml:"name"`
+	Active bool   `json:"active" yaml:"active"`
+}
+
+func (entry profileEntry) String() string {
+	if entry.Active {
+		return "* " + entry.Name
+	}
+	return "  " + entry.Name
+}
+
+// configProfilesCmd represents the config profiles command
+var configProfilesCmd = &cobra.Command{
+	Use:   "profiles",
+	Short: "list the profiles defined in the configuration file",
+	Long: `List the profiles defined in the configuration file.  The active profile is
+marked with an asterisk.
+
+Profiles overlay the base settings using keys of the form
+profile.<name>.<key>, e.g.
+
+  profile.work.input_dir = ${HOME}/work/notes
+
+and are selected with --profile, MAJIC_PROFILE or the profile key.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		appConfig := core.Config()
+		activeProfile := appConfig.ActiveProfile()
+		for _, profile := range appConfig.Profiles() {
+			core.Output().Emit(profileEntry{profile, profile == activeProfile})
+		}
+		return nil
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configProfilesCmd)
+}
@@ -0,0 +1,38 @@
+/*
+Copyright © 2023 Mark Johnson
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/shelterbelt/majic-cli/majic/helpers/core"
+	"github.com/spf13/cobra"
+)
+
+// configSetCmd represents the config set command
+var configSetCmd = &cobra.Command{
+	Use:          "set <key> <value>",
+	Short:        "store a value for a configuration key in the configuration file",
+	Long:         ``,
+	Args:         cobra.ExactArgs(2),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		appConfig := core.Config()
+		if option, found := core.LookupConfigOption(args[0]); found {
+			if err := core.ValidateConfigValue(args[0], option, args[1]); err != nil {
+				return err
+			}
+		} else {
+			core.Logger().Warn((&core.UnknownConfigKeyError{Key: args[0]}).Error())
+		}
+		if !appConfig.Set(args[0], args[1]) {
+			return fmt.Errorf("could not set configuration key: %s", args[0])
+		}
+		return appConfig.Save()
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configSetCmd)
+}
@@ -0,0 +1,30 @@
+/*
+Copyright © 2023 Mark Johnson
+*/
+package cmd
+
+import (
+	"github.com/shelterbelt/majic-cli/majic/helpers/core"
+	"github.com/spf13/cobra"
+)
+
+// configUnsetCmd represents the config unset command
+var configUnsetCmd = &cobra.Command{
+	Use:          "unset <key>",
+	Short:        "remove a configuration key from the configuration file",
+	Long:         ``,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		appConfig := core.Config()
+		if !appConfig.Unset(args[0]) {
+			core.Output().DetailedOutput("Configuration key not present in configuration file: " + args[0])
+			return nil
+		}
+		return appConfig.Save()
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configUnsetCmd)
+}
@@ -0,0 +1,418 @@
+/*
+Copyright © 2023 Mark Johnson
+*/
+package cmd
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"debug/buildinfo"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shelterbelt/majic-cli/majic/helpers/core"
+	"github.com/shelterbelt/majic-cli/majic/helpers/plugin"
+	"github.com/spf13/cobra"
+)
+
+const flagKeyBundle string = "bundle"
+
+// Number of lines of the log file included in a bundle.
+const doctorBundleLogLines int = 500
+
+const (
+	doctorStatusPass string = "pass"
+	doctorStatusWarn string = "warn"
+	doctorStatusFail string = "fail"
+)
+
+// doctorCheck is the result of one of the doctor command's
+// checks.
+type doctorCheck struct {
+	Name   string `json:"name" yaml:"name"`
+	Status string `json:"status" yaml:"status"`
+	Detail string `json:"detail" yaml:"detail"`
+	err    error
+}
+
+func (check doctorCheck) String() string {
+	return strings.ToUpper(check.Status) + "  " + check.Name + ": " + check.Detail
+}
+
+func (check doctorCheck) style() core.Style {
+	switch check.Status {
+	case doctorStatusWarn:
+		return core.StyleWarning
+	case doctorStatusFail:
+		return core.StyleError
+	}
+	return core.StyleSuccess
+}
+
+func passCheck(name string, detail string) doctorCheck {
+	return doctorCheck{name, doctorStatusPass, detail, nil}
+}
+
+func warnCheck(name string, detail string) doctorCheck {
+	return doctorCheck{name, doctorStatusWarn, detail, nil}
+}
+
+func failCheck(name string, err error) doctorCheck {
+	return doctorCheck{name, doctorStatusFail, err.Error(), err}
+}
+
+// doctorError reports the checks that failed.  It unwraps to
+// their errors so that the exit code reflects what failed.
+type doctorError struct {
+	failed []error
+}
+
+func (e *doctorError) Error() string {
+	if len(e.failed) == 1 {
+		return "1 check failed"
+	}
+	return fmt.Sprintf("%d checks failed", len(e.failed))
+}
+
+func (e *doctorError) Unwrap() []error {
+	return e.failed
+}
+
+// doctorCmd represents the doctor command
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "check the configuration, directories and plugins for problems",
+	Long: `Check that the configuration file loads and holds valid values, that the
+plugins, input and output directories can be used, and that each plugin loads,
+was built with the same Go toolchain and dependencies as majic, and doesn't
+define commands that are already in use.
+
+Each check is reported as pass, warn or fail.  With --bundle, the results are
+written to a gzipped tar archive along with the effective configuration,
+environment and recent log entries, with secrets redacted, to share when
+reporting a problem.`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	Annotations:  map[string]string{annotationAllowConfigErrors: "true"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var checks []doctorCheck
+		checks = append(checks, checkConfiguration()...)
+		checks = append(checks, checkDirectories()...)
+		checks = append(checks, checkPlugins()...)
+		checks = append(checks, checkCommands()...)
+
+		var failed []error
+		warnings := 0
+		for _, check := range checks {
+			if core.Output().Structured() {
+				core.Output().Emit(check)
+			} else {
+				core.Output().StyledOutput(check.style(), check.String())
+			}
+			switch check.Status {
+			case doctorStatusWarn:
+				warnings++
+			case doctorStatusFail:
+				failed = append(failed, check.err)
+			}
+		}
+		core.Output().DetailedOutput(fmt.Sprintf("%d checks, %d warnings, %d failed", len(checks), warnings, len(failed)))
+
+		bundlePath, _ := cmd.Flags().GetString(flagKeyBundle)
+		if len(bundlePath) > 0 {
+			if err := writeDoctorBundle(bundlePath, checks); err != nil {
+				return core.PathError(bundlePath, err)
+			}
+			core.Output().StyledOutput(core.StyleSuccess, "Wrote diagnostic bundle: "+bundlePath)
+		}
+		if len(failed) > 0 {
+			return &doctorError{failed}
+		}
+		return nil
+	},
+}
+
+func checkConfiguration() []doctorCheck {
+	const name = "configuration"
+	if err := core.LoadConfig(); err != nil {
+		return []doctorCheck{failCheck(name, err)}
+	}
+	checks := []doctorCheck{passCheck(name, "loaded "+core.Config().FilePath())}
+	if _, err := os.Stat(core.Config().FilePath()); errors.Is(err, os.ErrNotExist) {
+		checks[0] = passCheck(name, "using the defaults, "+core.Config().FilePath()+" doesn't exist yet")
+	}
+	var valueError *core.ConfigValueError
+	for _, problem := range core.Config().Validate() {
+		if errors.As(problem, &valueError) {
+			checks = append(checks, failCheck(name, problem))
+		} else {
+			checks = append(checks, warnCheck(name, problem.Error()))
+		}
+	}
+	return checks
+}
+
+func checkDirectories() []doctorCheck {
+	var checks []doctorCheck
+	for _, key := range []string{core.ConfigKeyPluginsDir, core.ConfigKeyInputDir, core.ConfigKeyOutputDir} {
+		path, err := core.Config().GetPath(key)
+		if err != nil {
+			checks = append(checks, failCheck(key, err))
+			continue
+		}
+		info, err := os.Stat(path)
+		if errors.Is(err, os.ErrNotExist) {
+			checks = append(checks, warnCheck(key, path+" does not exist, run \"majic init\" to create it"))
+			continue
+		} else if err != nil {
+			checks = append(checks, failCheck(key, core.PathError(path, err)))
+			continue
+		} else if !info.IsDir() {
+			checks = append(checks, failCheck(key, fmt.Errorf("%s is not a directory", path)))
+			continue
+		}
+		if err := checkDirectoryReadable(path); err != nil {
+			checks = append(checks, failCheck(key, err))
+			continue
+		}
+		if key == core.ConfigKeyOutputDir {
+			if err := checkDirectoryWritable(path); err != nil {
+				checks = append(checks, failCheck(key, err))
+				continue
+			}
+		}
+		if key == core.ConfigKeyPluginsDir && info.Mode().Perm()&0022 != 0 {
+			checks = append(checks, warnCheck(key, fmt.Sprintf("%s is writable by other users (mode %04o), who could add plugins", path, info.Mode().Perm())))
+			continue
+		}
+		checks = append(checks, passCheck(key, path))
+	}
+	return checks
+}
+
+func checkDirectoryReadable(path string) error {
+	dir, err := os.Open(path)
+	if err == nil {
+		_, err = dir.Readdirnames(1)
+		dir.Close()
+	}
+	if err != nil && err != io.EOF {
+		return core.PathError(path, err)
+	}
+	return nil
+}
+
+func checkDirectoryWritable(path string) error {
+	file, err := os.CreateTemp(path, ".majic-doctor-*")
+	if err != nil {
+		return core.PathError(path, err)
+	}
+	file.Close()
+	return os.Remove(file.Name())
+}
+
+func checkPlugins() []doctorCheck {
+	pluginsDirPath, err := core.Config().GetPath(core.ConfigKeyPluginsDir)
+	if err != nil {
+		return nil
+	}
+	pluginPaths, _ := filepath.Glob(filepath.Join(pluginsDirPath, "*.so"))
+	loaded := map[string]plugin.LoadedPlugin{}
+	for _, loadedPlugin := range plugin.LoadedPlugins() {
+		loaded[loadedPlugin.Path] = loadedPlugin
+	}
+	var checks []doctorCheck
+	for _, pluginPath := range pluginPaths {
+		name := "plugin " + filepath.Base(pluginPath)
+		if err := checkPluginBuild(pluginPath); err != nil {
+			checks = append(checks, failCheck(name, &core.PluginLoadError{Path: pluginPath, Err: err}))
+			continue
+		}
+		loadedPlugin, found := loaded[pluginPath]
+		switch {
+		case !found:
+			checks = append(checks, warnCheck(name, "not loaded"))
+		case loadedPlugin.Err != nil:
+			checks = append(checks, failCheck(name, loadedPlugin.Err))
+		case len(loadedPlugin.Commands) == 0:
+			checks = append(checks, warnCheck(name, "loaded, but adds no commands"))
+		default:
+			checks = append(checks, passCheck(name, "loaded, adds "+strings.Join(loadedPlugin.Commands, ", ")))
+		}
+	}
+	if len(checks) == 0 {
+		checks = append(checks, passCheck("plugins", "no plugins in "+pluginsDirPath))
+	}
+	return checks
+}
+
+// checkPluginBuild compares the Go toolchain and the module
+// versions recorded in the plugin with those of majic, all of
+// which must match for the plugin to load.
+func checkPluginBuild(pluginPath string) error {
+	pluginInfo, err := buildinfo.ReadFile(pluginPath)
+	if err != nil {
+		return err
+	}
+	if pluginInfo.GoVersion != runtime.Version() {
+		return fmt.Errorf("built with %s, majic was built with %s", pluginInfo.GoVersion, runtime.Version())
+	}
+	majicInfo, found := debug.ReadBuildInfo()
+	if !found {
+		return nil
+	}
+	versions := map[string]string{}
+	for _, module := range majicInfo.Deps {
+		versions[module.Path] = moduleVersion(module)
+	}
+	var mismatches []string
+	for _, module := range pluginInfo.Deps {
+		if module.Path == majicInfo.Main.Path {
+			// The majic module is usually replaced by a local
+			// copy while a plugin is developed, in which case
+			// its version can't be compared.
+			if module.Replace == nil && module.Version != majicInfo.Main.Version {
+				mismatches = append(mismatches, module.Path+" "+module.Version+" (majic is "+majicInfo.Main.Version+")")
+			}
+		} else if version, found := versions[module.Path]; found && version != moduleVersion(module) {
+			mismatches = append(mismatches, module.Path+" "+moduleVersion(module)+" (majic uses "+version+")")
+		}
+	}
+	if len(mismatches) > 0 {
+		return errors.New("built with different module versions: " + strings.Join(mismatches, ", "))
+	}
+	return nil
+}
+
+func moduleVersion(module *debug.Module) string {
+	if module.Replace != nil {
+		return module.Replace.Path + " " + module.Replace.Version
+	}
+	return module.Version
+}
+
+func checkCommands() []doctorCheck {
+	const name = "commands"
+	var checks []doctorCheck
+	for _, loadedPlugin := range plugin.LoadedPlugins() {
+		for _, conflict := range loadedPlugin.Conflicts {
+			checks = append(checks, failCheck(name, &core.PluginLoadError{Path: loadedPlugin.Path, Err: errors.New(conflict)}))
+		}
+	}
+	if len(checks) == 0 {
+		checks = append(checks, passCheck(name, "no plugin commands conflict"))
+	}
+	return checks
+}
+
+// writeDoctorBundle writes a gzipped tar archive holding the
+// checks, the effective configuration, the environment and the
+// most recent log entries, with secrets redacted.
+func writeDoctorBundle(path string, checks []doctorCheck) error {
+	report, err := json.MarshalIndent(checks, "", "  ")
+	if err != nil {
+		return err
+	}
+	files := []struct {
+		name     string
+		contents string
+	}{
+		{"doctor.json", string(report) + "\n"},
+		{"config.txt", bundleConfiguration()},
+		{"environment.txt", bundleEnvironment()},
+		{"majic.log", bundleLog()},
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	compressed := gzip.NewWriter(file)
+	archive := tar.NewWriter(compressed)
+	now := time.Now()
+	for _, bundled := range files {
+		header := &tar.Header{Name: "majic-doctor/" + bundled.name, Mode: 0600, Size: int64(len(bundled.contents)), ModTime: now}
+		if err := archive.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(archive, bundled.contents); err != nil {
+			return err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return err
+	}
+	if err := compressed.Close(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+func bundleConfiguration() string {
+	var builder strings.Builder
+	appConfig := core.Config()
+	builder.WriteString("# " + appConfig.FilePath() + "\n")
+	for _, key := range appConfig.Keys() {
+		builder.WriteString(configEntry{key, appConfig.DisplayValue(key), appConfig.Source(key).String()}.String() + "\n")
+	}
+	return builder.String()
+}
+
+func bundleEnvironment() string {
+	lines := []string{
+		"majic " + appVersion,
+		"go " + runtime.Version(),
+		"os " + runtime.GOOS + "/" + runtime.GOARCH,
+	}
+	var variables []string
+	for _, variable := range os.Environ() {
+		name, value, _ := strings.Cut(variable, "=")
+		if !strings.HasPrefix(name, core.EnvPrefix) && name != core.EnvKeyNoColor {
+			continue
+		}
+		key := strings.ToLower(strings.TrimPrefix(name, core.EnvPrefix))
+		variables = append(variables, name+"="+core.RedactValue(key, value))
+	}
+	sort.Strings(variables)
+	return strings.Join(append(lines, variables...), "\n") + "\n"
+}
+
+func bundleLog() string {
+	paths, err := core.Config().LogFilePaths()
+	if err != nil || len(paths) == 0 {
+		return ""
+	}
+	file, err := os.Open(paths[0])
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+	var entries []string
+	readLogLines(file, nil, func(line string) {
+		entries = append(entries, line)
+		if len(entries) > doctorBundleLogLines {
+			entries = entries[1:]
+		}
+	})
+	if len(entries) == 0 {
+		return ""
+	}
+	return strings.Join(entries, "\n") + "\n"
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+
+	// Here you will define your flags and configuration settings.
+	doctorCmd.Flags().String(flagKeyBundle, "", "write the results and a redacted copy of the configuration, environment and log to a .tar.gz file")
+}
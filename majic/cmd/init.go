@@ -0,0 +1,64 @@
+/*
+Copyright © 2023 Mark Johnson
+*/
+package cmd
+
+import (
+	"errors"
+	"os"
+
+	"github.com/shelterbelt/majic-cli/majic/helpers/core"
+	"github.com/spf13/cobra"
+)
+
+const flagKeyForce string = "force"
+
+// initCmd represents the init command
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "create the configuration file and the plugins, input and output directories",
+	Long: `Create the majic configuration file along with the plugins, input and output
+directories it refers to.
+
+The majic home defaults to ~/.majic and can be relocated with MAJIC_HOME.
+An existing configuration file is left untouched unless --force is given.`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	Annotations:  map[string]string{annotationAllowConfigErrors: "true"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		appConfig := core.Config()
+		force, _ := cmd.Flags().GetBool(flagKeyForce)
+		configFilePath := appConfig.FilePath()
+		_, err := os.Stat(configFilePath)
+		if err == nil && !force {
+			core.Output().NormalOutput("Using existing configuration file: " + configFilePath)
+		} else if err == nil || errors.Is(err, os.ErrNotExist) {
+			err = core.GenerateDefaultConfigFile(configFilePath, force)
+			if err != nil {
+				return err
+			}
+			core.Output().StyledOutput(core.StyleSuccess, "Created configuration file: "+configFilePath)
+		} else {
+			return err
+		}
+		for _, key := range []string{core.ConfigKeyPluginsDir, core.ConfigKeyInputDir, core.ConfigKeyOutputDir} {
+			dirPath, err := appConfig.GetPath(key)
+			if err != nil {
+				return err
+			}
+			err = os.MkdirAll(dirPath, 0750)
+			if err != nil {
+				return err
+			}
+			core.Output().NormalOutput("Directory ready: " + dirPath)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(initCmd)
+
+	// Here you will define your flags and configuration settings.
+	initCmd.Flags().Bool(flagKeyForce, false, "overwrite an existing configuration file with the defaults")
+}
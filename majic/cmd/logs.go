@@ -0,0 +1,148 @@
+/*
+Copyright © 2023 Mark Johnson
+*/
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/shelterbelt/majic-cli/majic/helpers/core"
+	"github.com/spf13/cobra"
+)
+
+const flagKeyLines string = "lines"
+const flagKeyFollow string = "follow"
+const flagKeyGrep string = "grep"
+const logsFollowInterval time.Duration = 500 * time.Millisecond
+
+// logsCmd represents the logs command
+var logsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "show recent entries from the log file",
+	Long: `Show the most recent entries from the log file configured with the log_file
+key, including the files kept by rotation.
+
+  majic logs -n 100
+  majic logs --grep 'level=(WARN|ERROR)'
+  majic logs --follow`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		core.CloseLogFile()
+		paths, err := core.Config().LogFilePaths()
+		if err != nil {
+			return err
+		}
+		if len(paths) == 0 {
+			return errors.New("no log file configured, set one with: majic config set " + core.ConfigKeyLogFile + " '${MAJIC_HOME}/logs/majic.log'")
+		}
+		lines, _ := cmd.Flags().GetInt(flagKeyLines)
+		follow, _ := cmd.Flags().GetBool(flagKeyFollow)
+		pattern, _ := cmd.Flags().GetString(flagKeyGrep)
+		var filter *regexp.Regexp
+		if len(pattern) > 0 {
+			filter, err = regexp.Compile(pattern)
+			if err != nil {
+				return err
+			}
+		}
+
+		var entries []string
+		for i := len(paths) - 1; i >= 0; i-- {
+			file, err := os.Open(paths[i])
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			} else if err != nil {
+				return err
+			}
+			_, err = readLogLines(file, filter, func(line string) {
+				entries = append(entries, line)
+				if lines > 0 && len(entries) > lines {
+					entries = entries[1:]
+				}
+			})
+			file.Close()
+			if err != nil {
+				return err
+			}
+		}
+		for _, entry := range entries {
+			core.Output().NormalOutput(entry)
+		}
+		if follow {
+			return followLogFile(paths[0], filter)
+		}
+		return nil
+	},
+}
+
+// readLogLines passes each line read from reader that matches
+// filter to handle and returns the number of bytes consumed.
+// A trailing partial line is left unread.
+func readLogLines(reader io.Reader, filter *regexp.Regexp, handle func(line string)) (int64, error) {
+	var consumed int64
+	bufferedReader := bufio.NewReader(reader)
+	for {
+		line, err := bufferedReader.ReadString('\n')
+		if err == io.EOF {
+			return consumed, nil
+		} else if err != nil {
+			return consumed, err
+		}
+		consumed += int64(len(line))
+		line = strings.TrimRight(line, "\r\n")
+		if filter == nil || filter.MatchString(line) {
+			handle(line)
+		}
+	}
+}
+
+// followLogFile prints entries as they are appended to the log
+// file, starting again from the beginning when it is rotated.
+func followLogFile(path string, filter *regexp.Regexp) error {
+	var offset int64
+	if info, err := os.Stat(path); err == nil {
+		offset = info.Size()
+	}
+	for {
+		time.Sleep(logsFollowInterval)
+		info, err := os.Stat(path)
+		if err != nil || info.Size() == offset {
+			continue
+		}
+		if info.Size() < offset {
+			offset = 0
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		_, err = file.Seek(offset, io.SeekStart)
+		if err == nil {
+			var consumed int64
+			consumed, err = readLogLines(file, filter, func(line string) {
+				core.Output().NormalOutput(line)
+			})
+			offset += consumed
+		}
+		file.Close()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(logsCmd)
+
+	// Here you will define your flags and configuration settings.
+	logsCmd.Flags().IntP(flagKeyLines, "n", 20, "number of entries to show, 0 for all")
+	logsCmd.Flags().BoolP(flagKeyFollow, "f", false, "keep printing entries as they are logged")
+	logsCmd.Flags().String(flagKeyGrep, "", "only show entries matching a regular expression")
+}
@@ -4,10 +4,12 @@ Copyright © 2023 Mark Johnson
 package cmd
 
 import (
+	"io"
 	"os"
 
 	"github.com/shelterbelt/majic-cli/majic/helpers/core"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -19,14 +21,54 @@ var rootCmd = &cobra.Command{
 	// has an action associated with it:
 	// Run: func(cmd *cobra.Command, args []string) {
 	// },
+	// Applies the flags of every command, including those
+	// added by plugins, so that commands don't have to.
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		commandStarted = true
+		core.SetOutputWriters(cmd.OutOrStdout(), cmd.ErrOrStderr())
+		// Usage is only shown for errors in the command line,
+		// which Cobra reports before this hook runs.
+		cmd.SilenceUsage = true
+		if err := core.LoadConfig(); err != nil {
+			if cmd.Annotations[annotationAllowConfigErrors] != "true" {
+				return err
+			}
+			core.Logger().Warn(err.Error())
+		}
+		core.Config().ApplyFlags(cmd.Flags())
+		if cmd.Annotations[annotationMigratesConfig] != "true" && len(core.Config().PendingMigrations()) > 0 {
+			core.Logger().Warn("configuration file " + core.Config().FilePath() + " is out of date, run \"majic config migrate\" to update it")
+		}
+		return nil
+	},
+	SilenceErrors: true,
 }
 
+// annotationAllowConfigErrors marks commands that run with the
+// default settings when the configuration can't be loaded, such
+// as those used to repair it.
+const annotationAllowConfigErrors string = "majic.allowConfigErrors"
+
+// annotationMigratesConfig marks the command that updates an
+// out of date configuration file, which doesn't need to be
+// warned about it.
+const annotationMigratesConfig string = "majic.migratesConfig"
+
+// commandStarted is set once the command line has been parsed
+// and validated, so that earlier errors can be reported as
+// usage errors.
+var commandStarted bool
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
 	err := rootCmd.Execute()
 	if err != nil {
-		os.Exit(1)
+		if !commandStarted && core.ExitCode(err) == core.ExitCodeFailure {
+			err = &core.UsageError{Err: err}
+		}
+		core.ReportError(err)
+		os.Exit(core.ExitCode(err))
 	}
 }
 
@@ -34,15 +76,47 @@ func GetRootCommand() *cobra.Command {
 	return rootCmd
 }
 
+// ParseEarlyFlags parses the global flags from args ahead of
+// Cobra so that settings needed to load the configuration and
+// plugins are known before the command stack is executed.
+// Unknown flags and parse errors are ignored here and are
+// reported by Cobra when the command executes.
+func ParseEarlyFlags(args []string) *pflag.FlagSet {
+	flags := pflag.NewFlagSet(rootCmd.Name(), pflag.ContinueOnError)
+	flags.ParseErrorsWhitelist.UnknownFlags = true
+	flags.SetOutput(io.Discard)
+	flags.Usage = func() {}
+	addGlobalFlags(flags)
+	flags.Parse(args)
+	return flags
+}
+
+func addGlobalFlags(flags *pflag.FlagSet) {
+	flags.Bool(core.FlagKeyDetailedOutput, core.DefaultDetailedOutput, "detailed output")
+	flags.Bool(core.FlagKeyVerboseOutput, core.DefaultVerboseOutput, "verbose output (i.e. everything)")
+	flags.BoolP(core.FlagKeyQuiet, "q", false, "don't report progress")
+	flags.Bool(core.FlagKeyNoColor, false, "don't style output, also disabled by "+core.EnvKeyNoColor)
+	flags.String(core.FlagKeyOutputFormat, "", "format of command results: text, json or yaml")
+	flags.String(core.FlagKeyLogFormat, "", "encoding of diagnostics written to stderr: text, logfmt or json")
+	flags.String(core.FlagKeyConfig, "", "configuration file (overrides "+core.EnvKeyConfig+", default is $"+core.EnvKeyHome+"/"+core.AppConfigFile+")")
+	flags.String(core.FlagKeyPluginsDir, "", "plugins directory (overrides "+core.EnvKey(core.ConfigKeyPluginsDir)+" and the configuration file)")
+	flags.String(core.FlagKeyInputDir, "", "input directory (overrides "+core.EnvKey(core.ConfigKeyInputDir)+" and the configuration file)")
+	flags.String(core.FlagKeyProfile, "", "configuration profile to apply (overrides "+core.EnvKey(core.ConfigKeyProfile)+" and the configuration file)")
+	flags.Bool(core.FlagKeyNoProjectConfig, false, "ignore project configuration files found above the working directory")
+	flags.String(core.FlagKeyOutputDir, "", "output directory (overrides "+core.EnvKey(core.ConfigKeyOutputDir)+" and the configuration file)")
+}
+
 func init() {
 	// Here you will define your flags and configuration settings.
 
-	rootCmd.PersistentFlags().Bool(core.FlagKeyDetailedOutput, core.DefaultDetailedOutput, "detailed output")
-	rootCmd.PersistentFlags().Bool(core.FlagKeyVerboseOutput, core.DefaultVerboseOutput, "verbose output (i.e. everything)")
+	// Run the root command's hooks even when a subcommand, such
+	// as one added by a plugin, defines hooks of its own.
+	cobra.EnableTraverseRunHooks = true
 
-	// Cobra supports persistent flags, which, if defined here,
-	// will be global for your application.
-	// rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.cbrapp.yaml)")
+	addGlobalFlags(rootCmd.PersistentFlags())
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return &core.UsageError{Err: err}
+	})
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
@@ -4,23 +4,61 @@ Copyright © 2023 Mark Johnson
 package cmd
 
 import (
+	"strings"
+
 	"github.com/shelterbelt/majic-cli/majic/helpers/core"
 	"github.com/spf13/cobra"
 )
 
+const appVersion string = "1.0.0"
+
+// versionInfo is the result of the version command.
+type versionInfo struct {
+	Version    string `json:"version" yaml:"version"`
+	ConfigFile string `json:"config_file" yaml:"config_file"`
+	PluginsDir string `json:"plugins_dir" yaml:"plugins_dir"`
+	InputDir   string `json:"input_dir" yaml:"input_dir"`
+	OutputDir  string `json:"output_dir" yaml:"output_dir"`
+}
+
+func (info versionInfo) String() string {
+	return strings.Join([]string{
+		"majic CLI " + info.Version,
+		"Configuration file: " + info.ConfigFile,
+		"Plugins directory: " + info.PluginsDir,
+		"Input directory: " + info.InputDir,
+		"Output directory: " + info.OutputDir,
+	}, "\n")
+}
+
 // versionCmd represents the version command
 var versionCmd = &cobra.Command{
 	Use:     "version",
 	Aliases: []string{"ver"},
 	Short:   "version informataion for the application",
 	Long:    ``,
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		appConfig := core.Config()
-		appConfig.ApplyFlags(cmd.Flags())
-		core.Output().NormalOutput("majic CLI 1.0.0")
-		core.Output().NormalOutput("Plugins directory: " + appConfig.Get(core.ConfigKeyPluginsDir).(string))
-		core.Output().NormalOutput("Input directory: " + appConfig.Get(core.ConfigKeyInputDir).(string))
-		core.Output().NormalOutput("Output directory: " + appConfig.Get(core.ConfigKeyOutputDir).(string))
+		pluginsDirPath, err := appConfig.GetPath(core.ConfigKeyPluginsDir)
+		if err != nil {
+			return err
+		}
+		inputDirPath, err := appConfig.GetPath(core.ConfigKeyInputDir)
+		if err != nil {
+			return err
+		}
+		outputDirPath, err := appConfig.GetPath(core.ConfigKeyOutputDir)
+		if err != nil {
+			return err
+		}
+		core.Output().Emit(versionInfo{
+			Version:    appVersion,
+			ConfigFile: appConfig.FilePath(),
+			PluginsDir: pluginsDirPath,
+			InputDir:   inputDirPath,
+			OutputDir:  outputDirPath,
+		})
+		return nil
 	},
 }
 
@@ -3,9 +3,12 @@ module github.com/shelterbelt/majic-cli/majic
 go 1.24
 
 require (
+	github.com/bmatcuk/doublestar/v4 v4.9.1
 	github.com/magiconair/properties v1.8.10
+	github.com/pelletier/go-toml/v2 v2.2.4
 	github.com/spf13/cobra v1.9.1
 	github.com/spf13/pflag v1.0.6
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require github.com/inconshreveable/mousetrap v1.1.0 // indirect
@@ -6,9 +6,10 @@ package core
 import (
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
-	"strconv"
+	"sort"
 	"strings"
 
 	"github.com/magiconair/properties"
@@ -24,83 +25,275 @@ const AppConfigFile string = "clirc"
 const ConfigKeyPluginsDir string = "plugins_dir"
 const ConfigKeyInputDir string = "input_dir"
 const ConfigKeyOutputDir string = "output_dir"
-const DefaultPluginsDir string = "${HOME}/.majic/plugins"
-const DefaultInputDir string = "${HOME}/.majic/input"
-const DefaultOutputDir string = "${HOME}/.majic/output"
+const ConfigKeyProfile string = "profile"
+const ConfigKeyNoProjectConfig string = "no_project_config"
+const ConfigKeyJobs string = "jobs"
+const ConfigKeyInclude string = "include"
+const ConfigKeyExclude string = "exclude"
+const ConfigKeyDryRun string = "dry_run"
+const ConfigKeyDiff string = "diff"
+const ProfileKeyPrefix string = "profile."
+const DefaultPluginsDir string = "${MAJIC_HOME}/plugins"
+const DefaultInputDir string = "${MAJIC_HOME}/input"
+const DefaultOutputDir string = "${MAJIC_HOME}/output"
 const FlagKeyDetailedOutput string = "detailed"
 const FlagKeyVerboseOutput string = "verbose"
+const FlagKeyQuiet string = "quiet"
 const DefaultDetailedOutput bool = false
 const DefaultVerboseOutput bool = false
+const FlagKeyPluginsDir string = "plugins-dir"
+const FlagKeyInputDir string = "input-dir"
+const FlagKeyOutputDir string = "output-dir"
+const FlagKeyProfile string = "profile"
+const FlagKeyConfig string = "config"
+const FlagKeyNoProjectConfig string = "no-project-config"
+const FlagKeyJobs string = "jobs"
+const FlagKeyInclude string = "include"
+const FlagKeyExclude string = "exclude"
+const FlagKeyDryRun string = "dry-run"
+const FlagKeyDiff string = "diff"
+const EnvPrefix string = "MAJIC_"
+const EnvKeyHome string = EnvPrefix + "HOME"
+const EnvKeyConfig string = EnvPrefix + "CONFIG"
+
+// ConfigSource identifies the configuration layer that
+// supplied a value.  Layers are consulted from highest
+// to lowest precedence:  command line flags, MAJIC_*
+// environment variables, the active profile, project
+// configuration files, the user configuration file, and
+// finally the compiled defaults.
+type ConfigSource int
+
+const (
+	SourceNone ConfigSource = iota
+	SourceDefault
+	SourceFile
+	SourceProject
+	SourceProfile
+	SourceEnvironment
+	SourceFlag
+)
+
+func (source ConfigSource) String() string {
+	switch source {
+	case SourceDefault:
+		return "default"
+	case SourceFile:
+		return "file"
+	case SourceProject:
+		return "project"
+	case SourceProfile:
+		return "profile"
+	case SourceEnvironment:
+		return "environment"
+	case SourceFlag:
+		return "flag"
+	}
+	return "none"
+}
+
+// Printer writes command results to stdout and diagnostics to
+// stderr.  AppOutput is the implementation used by majic; tests
+// can direct it to buffers with SetOutputWriters, or replace it
+// altogether with SetOutput.
+type Printer interface {
+	NormalOutput(message string)
+	StyledOutput(style Style, message string)
+	DetailedOutput(message string)
+	VerboseOutput(message string)
+	Emit(record any)
+	Structured() bool
+	ShowProgress() bool
+	Stdout() io.Writer
+	Stderr() io.Writer
+}
 
 type AppOutput struct {
-	// Currently using Cobra for all CLI flag processing.
+	// The logging-level is resolved like any other setting,
+	// so a flag such as --verbose=false unsets a level enabled
+	// in the configuration file.
 	//
-	// An unfortunate side-effect of this is that insight
-	// into CLI flags isn't available until the command
-	// stack is executed. The primary implication being
-	// that all application initialization logic prior to
-	// the execution of the command stack, is unaware of
-	// logging-level flags specified via the command line.
+	// The global flags are parsed ahead of Cobra (see
+	// SetStartupFlags) so that the levels are known while the
+	// configuration and plugins are loaded, and are applied
+	// again once the command stack is executed.
 	//
-	// There's nothing preventing evaluating CLI args
-	// directly or changing where the initialization
-	// logic is initiated, but either approach would
-	// need a decent amount of time to implement.
-	//
-	// The current solution for this is to support
-	// specifying the desired logging-level via the
-	// configuration file.  The co-mingled use of specifying
-	// logging-level settings via both the configuration
-	// file and command line flags is evaluated in an
-	// additive sense, meaning that a true value specified
-	// in the configuation file cannot be unset via a flag
-	// and vice-versa.
+	// Only NormalOutput writes to stdout.  Detailed and
+	// verbose output are diagnostics and are logged to stderr
+	// at the info and debug levels (see Logger).
 	detailed bool
 	verbose  bool
+	quiet    bool
+	color    bool
+	format   OutputFormat
+	emitted  bool
+	stdout   io.Writer
+	stderr   io.Writer
 }
 
 var output *AppOutput
+var printer Printer
+
+func Output() Printer {
+	if printer != nil {
+		return printer
+	}
+	return appOutput()
+}
 
-func Output() *AppOutput {
+func appOutput() *AppOutput {
 	if output == nil {
-		output = &AppOutput{DefaultDetailedOutput, DefaultVerboseOutput}
+		output = NewAppOutput(os.Stdout, os.Stderr)
 	}
 	return output
 }
 
+// NewAppOutput returns an AppOutput writing results to stdout
+// and diagnostics to stderr.
+func NewAppOutput(stdout io.Writer, stderr io.Writer) *AppOutput {
+	return &AppOutput{
+		detailed: DefaultDetailedOutput,
+		verbose:  DefaultVerboseOutput,
+		color:    !colorDisabledByEnvironment(),
+		format:   DefaultOutputFormat,
+		stdout:   stdout,
+		stderr:   stderr,
+	}
+}
+
+// SetOutput replaces the Printer returned by Output, or
+// restores majic's own when printer is nil.
+func SetOutput(replacement Printer) {
+	printer = replacement
+}
+
+// SetOutputWriters directs majic's output to stdout and stderr.
+// The root command applies the writers set with cobra's SetOut
+// and SetErr before each execution.  A nil writer restores the
+// process's stdout or stderr.
+func SetOutputWriters(stdout io.Writer, stderr io.Writer) {
+	appOutput().SetWriters(stdout, stderr)
+}
+
+func (output *AppOutput) SetWriters(stdout io.Writer, stderr io.Writer) {
+	if stdout == nil {
+		stdout = os.Stdout
+	}
+	if stderr == nil {
+		stderr = os.Stderr
+	}
+	output.stdout = stdout
+	output.stderr = stderr
+	output.emitted = false
+}
+
+func (output *AppOutput) Stdout() io.Writer {
+	return output.stdout
+}
+
+func (output *AppOutput) Stderr() io.Writer {
+	return output.stderr
+}
+
+func (output *AppOutput) SetDetailed(detailed bool) {
+	output.detailed = detailed
+	setLogLevel(output.detailed, output.verbose)
+}
+
+func (output *AppOutput) SetVerbose(verbose bool) {
+	output.verbose = verbose
+	setLogLevel(output.detailed, output.verbose)
+}
+
+// SetQuiet suppresses progress reporting.
+func (output *AppOutput) SetQuiet(quiet bool) {
+	output.quiet = quiet
+}
+
+// ShowProgress reports whether long running operations should
+// report their progress on stderr, which is the case unless
+// --quiet is set or results are written in a structured format.
+func (output *AppOutput) ShowProgress() bool {
+	return !output.quiet && !output.Structured()
+}
+
+// NormalOutput writes a result message to stdout.  In the
+// structured output formats the message is emitted as a record
+// with a single message field.
 func (output *AppOutput) NormalOutput(message string) {
+	if output.Structured() {
+		output.Emit(messageRecord{strings.TrimSuffix(message, "\n")})
+		return
+	}
+	if logFile != nil {
+		logFileResult(strings.TrimSuffix(message, "\n"))
+	}
 	output.print(message)
 }
 
 func (output *AppOutput) DetailedOutput(message string) {
-	if output.detailed || output.verbose {
-		output.print(message)
-	}
+	Logger().Info(strings.TrimSuffix(message, "\n"))
 }
 
 func (output *AppOutput) VerboseOutput(message string) {
-	if output.verbose {
-		output.print(message)
-	}
+	Logger().Debug(strings.TrimSuffix(message, "\n"))
 }
 
 func (output *AppOutput) print(message string) {
 	if strings.HasSuffix(message, "\n") {
-		fmt.Print(message)
+		fmt.Fprint(output.stdout, message)
 	} else {
-		fmt.Println(message)
+		fmt.Fprintln(output.stdout, message)
 	}
 }
 
 type AppConfig struct {
+	defaultSettings    *properties.Properties
 	configFileSettings *properties.Properties
+	projectSettings    *properties.Properties
+	flagSettings       *properties.Properties
+	configFilePath     string
+	projectFilePaths   []string
+	secrets            map[string]string
+
+	// savedSettings are the configuration file settings as
+	// written, without the pending migrations applied in memory.
+	savedSettings *properties.Properties
 }
 
 var appConfig *AppConfig
+var appConfigErr error
+var startupFlags *pflag.FlagSet
+
+// SetStartupFlags supplies the global flags parsed ahead of
+// Cobra so that they apply while the configuration and plugins
+// are loaded.  It must be called before the configuration is
+// first accessed.
+func SetStartupFlags(flags *pflag.FlagSet) {
+	startupFlags = flags
+	if configFilePath, err := flags.GetString(FlagKeyConfig); err == nil && len(configFilePath) > 0 {
+		SetConfigFile(configFilePath)
+	}
+}
+
+// LoadConfig loads the configuration files.  It returns a
+// ConfigError when they can't be used, in which case the
+// configuration falls back to the defaults.
+func LoadConfig() error {
+	if appConfig == nil {
+		appConfigErr = loadConfig()
+	}
+	return appConfigErr
+}
 
+// Config returns the configuration, loading it on first use.
+// Problems loading it are logged; call LoadConfig first to
+// handle them instead.
 func Config() *AppConfig {
 	if appConfig == nil {
-		appConfig = loadConfig()
+		if err := LoadConfig(); err != nil {
+			Logger().Warn(err.Error())
+		}
 	}
 	return appConfig
 }
@@ -110,96 +303,365 @@ func (config *AppConfig) Get(key string) interface{} {
 }
 
 func (config *AppConfig) GetD(key string, defaultVal string) interface{} {
-	value, found := config.configFileSettings.Get(key)
+	value, _, found := config.Lookup(key)
 	if !found {
-		Output().NormalOutput("Could not retrieve configuration value for key: " + key)
+		Logger().Warn("could not retrieve configuration value", "key", key)
 		value = defaultVal
 	}
 	return value
 }
 
+// Lookup resolves the effective value for key by consulting
+// each configuration layer in order of precedence and reports
+// the layer the value came from.
+func (config *AppConfig) Lookup(key string) (string, ConfigSource, bool) {
+	if value, found := config.flagSettings.Get(key); found {
+		return value, SourceFlag, true
+	}
+	if value, found := os.LookupEnv(EnvKey(key)); found {
+		return value, SourceEnvironment, true
+	}
+	if key != ConfigKeyProfile {
+		if profile := config.ActiveProfile(); len(profile) > 0 {
+			for _, settings := range config.fileLayers() {
+				if value, found := settings.Get(ProfileKey(profile, key)); found {
+					return value, SourceProfile, true
+				}
+			}
+		}
+	}
+	if config.projectConfigEnabled() {
+		if value, found := config.projectSettings.Get(key); found {
+			return value, SourceProject, true
+		}
+	}
+	if value, found := config.configFileSettings.Get(key); found {
+		return value, SourceFile, true
+	}
+	if value, found := config.defaultSettings.Get(key); found {
+		return value, SourceDefault, true
+	}
+	return "", SourceNone, false
+}
+
+// Source reports which configuration layer supplies the
+// effective value for key.
+func (config *AppConfig) Source(key string) ConfigSource {
+	_, source, _ := config.Lookup(key)
+	return source
+}
+
+// ActiveProfile returns the name of the selected profile,
+// or an empty string when no profile is selected.  Profiles
+// are selected with the --profile flag, the MAJIC_PROFILE
+// environment variable or the profile key in a
+// configuration file.
+func (config *AppConfig) ActiveProfile() string {
+	if value, found := config.flagSettings.Get(ConfigKeyProfile); found {
+		return value
+	}
+	if value, found := os.LookupEnv(EnvKey(ConfigKeyProfile)); found {
+		return value
+	}
+	for _, settings := range config.fileLayers() {
+		if value, found := settings.Get(ConfigKeyProfile); found {
+			return value
+		}
+	}
+	return ""
+}
+
+// Profiles returns the sorted names of the profiles defined
+// in the configuration files.
+func (config *AppConfig) Profiles() []string {
+	profileSet := map[string]bool{}
+	for _, settings := range config.fileLayers() {
+		for _, key := range settings.Keys() {
+			if profileKey, found := strings.CutPrefix(key, ProfileKeyPrefix); found {
+				if name, _, found := strings.Cut(profileKey, "."); found {
+					profileSet[name] = true
+				}
+			}
+		}
+	}
+	profiles := make([]string, 0, len(profileSet))
+	for name := range profileSet {
+		profiles = append(profiles, name)
+	}
+	sort.Strings(profiles)
+	return profiles
+}
+
+// ProfileKey returns the configuration file key that
+// overrides key when profile is active.
+func ProfileKey(profile string, key string) string {
+	return ProfileKeyPrefix + profile + "." + key
+}
+
+func (config *AppConfig) checkActiveProfile() {
+	profile := config.ActiveProfile()
+	if len(profile) == 0 {
+		return
+	}
+	for _, name := range config.Profiles() {
+		if name == profile {
+			Output().DetailedOutput("Active profile: " + profile)
+			return
+		}
+	}
+	Logger().Warn("profile \"" + profile + "\" is not defined in any configuration file")
+}
+
 func (config *AppConfig) Set(key string, value interface{}) bool {
 	result := true
 	err := config.configFileSettings.SetValue(key, value)
+	if err == nil && config.savedSettings != config.configFileSettings {
+		err = config.savedSettings.SetValue(key, value)
+	}
 	if err != nil {
-		Output().NormalOutput("Could not set configuration value \"" + value.(string) + "\" for key: " + key)
+		Logger().Warn("could not set configuration value", "key", key, "value", RedactValue(key, fmt.Sprint(value)))
 		result = false
 	}
 	return result
 }
 
+// Unset removes key from the configuration file settings.
+// Values supplied by other layers are unaffected.
+func (config *AppConfig) Unset(key string) bool {
+	_, found := config.configFileSettings.Get(key)
+	config.configFileSettings.Delete(key)
+	config.savedSettings.Delete(key)
+	return found
+}
+
+// FileValue returns the unexpanded value of key as written
+// in the configuration file.
+func (config *AppConfig) FileValue(key string) (string, bool) {
+	value, found := config.configFileSettings.Map()[key]
+	return value, found
+}
+
+// Keys returns the sorted set of keys known to any
+// configuration layer.
+func (config *AppConfig) Keys() []string {
+	keySet := map[string]bool{}
+	layers := append([]*properties.Properties{config.defaultSettings, config.flagSettings}, config.fileLayers()...)
+	for _, layer := range layers {
+		for _, key := range layer.Keys() {
+			keySet[key] = true
+		}
+	}
+	keys := make([]string, 0, len(keySet))
+	for key := range keySet {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// FilePath returns the location of the configuration file.
+func (config *AppConfig) FilePath() string {
+	return config.configFilePath
+}
+
+// Save writes the configuration file settings back to disk.
+// Properties files keep their layout; structured formats are
+// rewritten in full.  Migrations applied in memory are left for
+// "majic config migrate".
+func (config *AppConfig) Save() error {
+	if _, err := os.Stat(config.configFilePath); errors.Is(err, os.ErrNotExist) {
+		// A configuration file created by saving starts from the
+		// settings written by GenerateDefaultConfigFile.
+		settings := defaultConfigFileSettings()
+		settings.Merge(config.savedSettings)
+		if err := os.MkdirAll(filepath.Dir(config.configFilePath), 0750); err != nil {
+			return err
+		}
+		config.configFileSettings = settings
+		config.savedSettings = settings
+	}
+	return writeSettingsFile(config.configFilePath, config.savedSettings)
+}
+
+// SaveAs writes the configuration file settings to path in the
+// format implied by its extension.
+func (config *AppConfig) SaveAs(path string) error {
+	return writeSettingsFile(path, config.configFileSettings)
+}
+
+// ApplyFlags adds the flags explicitly specified on the command
+// line to the flag layer and updates the logging settings.  The root
+// command applies its flags before any command runs, so commands
+// only need to call this for flags they parse themselves.
 func (config *AppConfig) ApplyFlags(flags *pflag.FlagSet) {
-	detailed, err := flags.GetBool(FlagKeyDetailedOutput)
+	profile := config.ActiveProfile()
+	changed := config.applyFlagSettings(flags)
+	problems := config.applyOutputSettings()
+	// Problems with the settings have already been reported
+	// unless the flags changed them.
+	if changed {
+		for _, problem := range problems {
+			Logger().Warn(problem.Error())
+		}
+	}
+	if config.ActiveProfile() != profile {
+		config.checkActiveProfile()
+	}
+}
+
+// applyFlagSettings reports whether the flags changed the
+// flag layer.
+func (config *AppConfig) applyFlagSettings(flags *pflag.FlagSet) bool {
+	changed := false
+	// Only flags explicitly specified on the command line
+	// participate in the flag layer so that unset flags
+	// don't mask the lower precedence layers.
+	flags.Visit(func(flag *pflag.Flag) {
+		key := ConfigKeyForFlag(flag.Name)
+		if _, found := config.defaultSettings.Get(key); found {
+			flagValue := flag.Value.String()
+			// Repeatable flags become comma separated lists.
+			if sliceValue, found := flag.Value.(pflag.SliceValue); found {
+				flagValue = strings.Join(sliceValue.GetSlice(), ",")
+			}
+			if value, found := config.flagSettings.Get(key); !found || value != flagValue {
+				config.flagSettings.Set(key, flagValue)
+				changed = true
+			}
+		}
+	})
+	return changed
+}
+
+// applyOutputSettings applies the keys that control majic's
+// output, returning the problems with values from flags and the
+// environment.  Validate reports those in configuration files.
+func (config *AppConfig) applyOutputSettings() []error {
+	var problems []error
+	report := func(key string, err error) {
+		if source := config.Source(key); source == SourceFlag || source == SourceEnvironment {
+			problems = append(problems, err)
+		}
+	}
+	format, err := config.GetString(ConfigKeyLogFormat)
+	if err == nil {
+		var parsed LogFormat
+		parsed, err = ParseLogFormat(format)
+		SetLogFormat(parsed)
+	}
+	if err != nil {
+		SetLogFormat(DefaultLogFormat)
+		report(ConfigKeyLogFormat, err)
+	}
+	format, err = config.GetString(ConfigKeyOutputFormat)
+	if err == nil {
+		var parsed OutputFormat
+		parsed, err = ParseOutputFormat(format)
+		appOutput().SetFormat(parsed)
+	}
 	if err != nil {
-		detailed = false
+		appOutput().SetFormat(DefaultOutputFormat)
+		report(ConfigKeyOutputFormat, err)
 	}
-	err = nil
-	verbose, err := flags.GetBool(FlagKeyVerboseOutput)
+	detailed, err := config.GetBool(FlagKeyDetailedOutput)
 	if err != nil {
-		verbose = false
+		report(FlagKeyDetailedOutput, err)
 	}
-	Output().detailed = Output().detailed || detailed
-	Output().verbose = Output().verbose || verbose
-	config.Set(FlagKeyDetailedOutput, Output().detailed)
-	config.Set(FlagKeyVerboseOutput, Output().verbose)
+	verbose, err := config.GetBool(FlagKeyVerboseOutput)
+	if err != nil {
+		report(FlagKeyVerboseOutput, err)
+	}
+	quiet, err := config.GetBool(FlagKeyQuiet)
+	if err != nil {
+		report(FlagKeyQuiet, err)
+	}
+	noColor, err := config.GetBool(ConfigKeyNoColor)
+	if err != nil {
+		report(ConfigKeyNoColor, err)
+	}
+	appOutput().SetDetailed(detailed)
+	appOutput().SetVerbose(verbose)
+	appOutput().SetQuiet(quiet)
+	appOutput().SetColor(!noColor && !colorDisabledByEnvironment())
+	return problems
 }
 
-// Should this be public?
-func loadConfig() *AppConfig {
-	appConfig = &AppConfig{loadConfigFile()}
-	detailed, err := strconv.ParseBool(appConfig.Get(FlagKeyDetailedOutput).(string))
-	if err != nil {
-		detailed = false
+// EnvKey returns the name of the environment variable
+// that overrides the configuration value for key.
+func EnvKey(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(key))
+}
+
+// ConfigKeyForFlag returns the configuration key that
+// a command line flag overrides.
+func ConfigKeyForFlag(flagName string) string {
+	return strings.ReplaceAll(flagName, "-", "_")
+}
+
+func loadConfig() error {
+	flagSettings := properties.NewProperties()
+	flagSettings.DisableExpansion = true
+	appConfig = &AppConfig{
+		defaultSettings:    configDefaults,
+		configFileSettings: properties.NewProperties(),
+		savedSettings:      properties.NewProperties(),
+		projectSettings:    properties.NewProperties(),
+		flagSettings:       flagSettings,
 	}
-	verbose, err := strconv.ParseBool(appConfig.Get(FlagKeyVerboseOutput).(string))
+	// Flags and environment variables are known before any
+	// file is read, so they already determine the logging
+	// settings used while the configuration files are loaded.
+	if startupFlags != nil {
+		appConfig.applyFlagSettings(startupFlags)
+	}
+	appConfig.applyOutputSettings()
+	configFileSettings, configFilePath, err := loadConfigFile()
+	appConfig.configFilePath = configFilePath
 	if err != nil {
-		verbose = false
+		return err
 	}
-	Output().detailed = detailed
-	Output().verbose = verbose
-	return appConfig
+	appConfig.configFileSettings = configFileSettings
+	appConfig.savedSettings = configFileSettings
+	appConfig.projectSettings, appConfig.projectFilePaths = loadProjectConfigFiles(appConfig.configFilePath)
+	for _, problem := range appConfig.applyOutputSettings() {
+		Logger().Warn(problem.Error())
+	}
+	for _, projectFilePath := range appConfig.projectFilePaths {
+		Output().VerboseOutput("Merged project configuration file: " + projectFilePath)
+	}
+	appConfig.checkActiveProfile()
+	if err := appConfig.openLogFile(); err != nil {
+		Logger().Warn("could not open log file", "error", err)
+	}
+	return nil
 }
 
-func loadConfigFile() *properties.Properties {
-	userHomeDirPath, err := os.UserHomeDir()
-	HandleError(err)
-	appConfigFilePath := filepath.Join(userHomeDirPath, AppConfigHome, AppConfigFile)
+func loadConfigFile() (*properties.Properties, string, error) {
+	homeDirPath, err := Home()
+	if err != nil {
+		return nil, "", &ConfigError{Err: err}
+	}
+	// Exporting the resolved home allows ${MAJIC_HOME} to be
+	// referenced by the defaults and the configuration files.
+	os.Setenv(EnvKeyHome, homeDirPath)
+	appConfigFilePath, explicit, err := configFileLocation(homeDirPath)
+	if err != nil {
+		return nil, "", &ConfigError{Err: err}
+	}
 	Output().DetailedOutput("Configuration file: " + appConfigFilePath)
 	_, err = os.Stat(appConfigFilePath)
-	if err != nil && !errors.Is(err, os.ErrExist) {
-		Output().DetailedOutput("Generating default properties...")
-		generateDefaultConfigFile()
-	}
-	configFileSettings, err := properties.LoadFile(appConfigFilePath, properties.UTF8)
-	HandleError(err)
-	return configFileSettings
-}
-
-func generateDefaultConfigFile() {
-	props := properties.NewProperties()
-	props.SetValue(ConfigKeyOutputDir, DefaultOutputDir)
-	props.SetValue(ConfigKeyInputDir, DefaultInputDir)
-	props.SetValue(ConfigKeyPluginsDir, DefaultPluginsDir)
-	userHomeDirPath, err := os.UserHomeDir()
-	HandleError(err)
-	appConfigFilePath := filepath.Join(userHomeDirPath, AppConfigHome)
-	err = os.Mkdir(appConfigFilePath, 0750)
-	if err != nil && !errors.Is(err, os.ErrExist) {
-		HandleError(err)
-	}
-	appConfigFilePath = filepath.Join(appConfigFilePath, AppConfigFile)
-	appConfigFile, err := os.OpenFile(appConfigFilePath, os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil && !errors.Is(err, os.ErrExist) {
-		HandleError(err)
-	}
-	defer appConfigFile.Close()
-	_, err = props.Write(appConfigFile, properties.UTF8)
-	HandleError(err)
-}
-
-func HandleError(e error) {
-	if e != nil {
-		Output().NormalOutput("Terminating due to error")
-		panic(e)
+	if errors.Is(err, os.ErrNotExist) {
+		if explicit {
+			return nil, appConfigFilePath, &ConfigError{appConfigFilePath, errors.New("configuration file not found")}
+		}
+		// The configuration file is only created by the commands
+		// that write to it, such as init and config set.
+		Output().DetailedOutput("Using default properties, configuration file not found")
+		return properties.NewProperties(), appConfigFilePath, nil
+	}
+	configFileSettings, err := loadSettingsFile(appConfigFilePath)
+	if err != nil {
+		return nil, appConfigFilePath, &ConfigError{appConfigFilePath, err}
 	}
+	return configFileSettings, appConfigFilePath, nil
 }
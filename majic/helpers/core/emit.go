@@ -0,0 +1,111 @@
+/*
+Copyright © 2023 Mark Johnson
+*/
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const ConfigKeyOutputFormat string = "output"
+const FlagKeyOutputFormat string = "output"
+
+// OutputFormat selects how command results are written to
+// stdout.  The structured formats write one JSON object per
+// line or one YAML document per record.
+type OutputFormat string
+
+const (
+	OutputFormatText OutputFormat = "text"
+	OutputFormatJSON OutputFormat = "json"
+	OutputFormatYAML OutputFormat = "yaml"
+)
+
+const DefaultOutputFormat OutputFormat = OutputFormatText
+
+// ParseOutputFormat converts a format name, such as the value
+// of the --output flag, to an OutputFormat.
+func ParseOutputFormat(name string) (OutputFormat, error) {
+	switch format := OutputFormat(strings.ToLower(name)); format {
+	case OutputFormatText, OutputFormatJSON, OutputFormatYAML:
+		return format, nil
+	case "yml":
+		return OutputFormatYAML, nil
+	}
+	return "", fmt.Errorf("unsupported output format: %s", name)
+}
+
+// logFileResult captures a command result in the log file so
+// that the log holds a complete record of the run.
+func logFileResult(record any) {
+	var text string
+	if stringer, found := record.(fmt.Stringer); found {
+		text = stringer.String()
+	} else {
+		text = fmt.Sprint(record)
+	}
+	options := &slog.HandlerOptions{Level: slog.LevelDebug}
+	slog.New(slog.NewTextHandler(logFile, options)).Info("output", "result", text)
+}
+
+// messageRecord carries NormalOutput messages in the
+// structured formats.
+type messageRecord struct {
+	Message string `json:"message" yaml:"message"`
+}
+
+func (output *AppOutput) SetFormat(format OutputFormat) {
+	output.format = format
+}
+
+func (output *AppOutput) Format() OutputFormat {
+	return output.format
+}
+
+// Structured reports whether results are being written in a
+// machine-readable format.
+func (output *AppOutput) Structured() bool {
+	return output.format == OutputFormatJSON || output.format == OutputFormatYAML
+}
+
+// Emit writes a command result to stdout.  In text mode the
+// record is printed using its String method, if it has one;
+// otherwise it is encoded in the selected structured format
+// using its json or yaml field tags.
+func (output *AppOutput) Emit(record any) {
+	if logFile != nil {
+		logFileResult(record)
+	}
+	switch output.format {
+	case OutputFormatJSON:
+		encoded, err := json.Marshal(record)
+		if err != nil {
+			Logger().Error("could not encode result", "error", err)
+			return
+		}
+		output.stdout.Write(append(encoded, '\n'))
+	case OutputFormatYAML:
+		encoded, err := yaml.Marshal(record)
+		if err != nil {
+			Logger().Error("could not encode result", "error", err)
+			return
+		}
+		if output.emitted {
+			io.WriteString(output.stdout, "---\n")
+		}
+		output.stdout.Write(encoded)
+	default:
+		if stringer, found := record.(fmt.Stringer); found {
+			output.print(stringer.String())
+		} else {
+			output.print(fmt.Sprint(record))
+		}
+	}
+	output.emitted = true
+}
@@ -0,0 +1,205 @@
+/*
+Copyright © 2023 Mark Johnson
+*/
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+const ConfigKeyOnError string = "on_error"
+const FlagKeyOnError string = "on-error"
+const ConfigKeyMaxErrors string = "max_errors"
+const FlagKeyMaxErrors string = "max-errors"
+const ConfigKeyErrorReport string = "error_report"
+const FlagKeyErrorReport string = "error-report"
+
+// ErrorPolicy selects whether processing a directory of files
+// stops at the first file that fails or continues with the
+// remaining files.
+type ErrorPolicy string
+
+const (
+	ErrorPolicyFailFast ErrorPolicy = "fail-fast"
+	ErrorPolicyContinue ErrorPolicy = "continue"
+)
+
+const DefaultErrorPolicy ErrorPolicy = ErrorPolicyFailFast
+
+// ParseErrorPolicy converts a policy name, such as the value
+// of the --on-error flag, to an ErrorPolicy.
+func ParseErrorPolicy(name string) (ErrorPolicy, error) {
+	switch policy := ErrorPolicy(strings.ToLower(name)); policy {
+	case ErrorPolicyFailFast, ErrorPolicyContinue:
+		return policy, nil
+	}
+	return "", fmt.Errorf("unsupported error policy: %s", name)
+}
+
+// Exit codes returned by majic, documented in the README.
+const (
+	ExitCodeSuccess    int = 0
+	ExitCodeFailure    int = 1
+	ExitCodeUsage      int = 2
+	ExitCodeConfig     int = 3
+	ExitCodeNotFound   int = 4
+	ExitCodePermission int = 5
+	ExitCodePlugin     int = 6
+	ExitCodeProcessing int = 7
+)
+
+// UsageError reports invalid command line arguments or flags.
+type UsageError struct {
+	Err error
+}
+
+func (e *UsageError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *UsageError) Unwrap() error {
+	return e.Err
+}
+
+// ConfigError reports a configuration file or setting that
+// can't be used.
+type ConfigError struct {
+	Path string
+	Err  error
+}
+
+func (e *ConfigError) Error() string {
+	if len(e.Path) == 0 {
+		return "configuration error: " + e.Err.Error()
+	}
+	return "configuration error in " + e.Path + ": " + e.Err.Error()
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// InputNotFoundError reports a file or directory to process
+// that doesn't exist.
+type InputNotFoundError struct {
+	Path string
+}
+
+func (e *InputNotFoundError) Error() string {
+	return "input not found: " + e.Path
+}
+
+func (e *InputNotFoundError) Unwrap() error {
+	return fs.ErrNotExist
+}
+
+// PermissionError reports a file or directory that majic isn't
+// allowed to read or write.
+type PermissionError struct {
+	Path string
+	Err  error
+}
+
+func (e *PermissionError) Error() string {
+	return "permission denied: " + e.Path
+}
+
+func (e *PermissionError) Unwrap() error {
+	return e.Err
+}
+
+// PluginLoadError reports a plugin that couldn't be loaded.
+type PluginLoadError struct {
+	Path string
+	Err  error
+}
+
+func (e *PluginLoadError) Error() string {
+	return "could not load plugin " + e.Path + ": " + e.Err.Error()
+}
+
+func (e *PluginLoadError) Unwrap() error {
+	return e.Err
+}
+
+// ProcessorError reports a failure while a processor handled
+// a file.
+type ProcessorError struct {
+	Path string
+	Err  error
+}
+
+func (e *ProcessorError) Error() string {
+	return "could not process " + e.Path + ": " + e.Err.Error()
+}
+
+func (e *ProcessorError) Unwrap() error {
+	return e.Err
+}
+
+// PathError converts an error from a file system operation on
+// path to an InputNotFoundError or PermissionError where that
+// applies, and otherwise returns err unchanged.
+func PathError(path string, err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, fs.ErrNotExist):
+		return &InputNotFoundError{path}
+	case errors.Is(err, fs.ErrPermission):
+		return &PermissionError{path, err}
+	}
+	return err
+}
+
+// ExitCode returns the process exit code for err.
+func ExitCode(err error) int {
+	var usageError *UsageError
+	var configError *ConfigError
+	var configValueError *ConfigValueError
+	var notFoundError *InputNotFoundError
+	var permissionError *PermissionError
+	var pluginError *PluginLoadError
+	var processorError *ProcessorError
+	switch {
+	case err == nil:
+		return ExitCodeSuccess
+	case errors.As(err, &usageError):
+		return ExitCodeUsage
+	case errors.As(err, &configError), errors.As(err, &configValueError):
+		return ExitCodeConfig
+	case errors.As(err, &pluginError):
+		return ExitCodePlugin
+	case errors.As(err, &notFoundError):
+		return ExitCodeNotFound
+	case errors.As(err, &permissionError), errors.Is(err, fs.ErrPermission):
+		return ExitCodePermission
+	case errors.As(err, &processorError):
+		return ExitCodeProcessing
+	}
+	return ExitCodeFailure
+}
+
+// ReportError logs err as a single line and, with --verbose,
+// the chain of errors that caused it.
+func ReportError(err error) {
+	Logger().Error(err.Error())
+	for cause := errors.Unwrap(err); cause != nil; cause = errors.Unwrap(cause) {
+		Logger().Debug("caused by", "type", fmt.Sprintf("%T", cause), "error", cause.Error())
+	}
+}
+
+// HandleError reports err and exits with the matching exit code.
+//
+// Deprecated: return errors from helpers and from a command's
+// RunE instead, which majic reports in the same way.
+func HandleError(e error) {
+	if e != nil {
+		ReportError(e)
+		os.Exit(ExitCode(e))
+	}
+}
@@ -0,0 +1,371 @@
+/*
+Copyright © 2023 Mark Johnson
+*/
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/magiconair/properties"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFormat identifies the syntax of a configuration file.
+// Structured formats are mapped onto the same key space as
+// properties files, with nested tables becoming dotted keys
+// and lists becoming comma separated values.
+type ConfigFormat string
+
+const (
+	FormatProperties ConfigFormat = "properties"
+	FormatYAML       ConfigFormat = "yaml"
+	FormatTOML       ConfigFormat = "toml"
+	FormatJSON       ConfigFormat = "json"
+)
+
+// configFileExtensions lists the supported extensions in the
+// order they are searched for.  Structured formats take
+// precedence over a properties file with no extension.
+var configFileExtensions = []string{".yaml", ".yml", ".toml", ".json", ""}
+
+// ParseConfigFormat converts a format name, such as the value
+// of a command line flag, to a ConfigFormat.
+func ParseConfigFormat(name string) (ConfigFormat, error) {
+	switch strings.ToLower(strings.TrimPrefix(name, ".")) {
+	case "properties", "props":
+		return FormatProperties, nil
+	case "yaml", "yml":
+		return FormatYAML, nil
+	case "toml":
+		return FormatTOML, nil
+	case "json":
+		return FormatJSON, nil
+	}
+	return "", fmt.Errorf("unsupported configuration format: %s", name)
+}
+
+// ConfigFormatForPath determines the format of a configuration
+// file from its extension.  Files without a recognized
+// extension are treated as properties files.
+func ConfigFormatForPath(path string) ConfigFormat {
+	format, err := ParseConfigFormat(filepath.Ext(path))
+	if err != nil {
+		return FormatProperties
+	}
+	return format
+}
+
+// ConfigFilePathForFormat returns the path of a configuration
+// file equivalent to path but written in format.
+func ConfigFilePathForFormat(path string, format ConfigFormat) string {
+	if ConfigFormatForPath(path) != FormatProperties || filepath.Ext(path) == ".properties" {
+		path = strings.TrimSuffix(path, filepath.Ext(path))
+	}
+	switch format {
+	case FormatYAML:
+		return path + ".yaml"
+	case FormatTOML:
+		return path + ".toml"
+	case FormatJSON:
+		return path + ".json"
+	}
+	return path
+}
+
+// findConfigFile returns the first existing file named base
+// with one of the supported extensions.
+func findConfigFile(base string) (string, bool) {
+	for _, extension := range configFileExtensions {
+		info, err := os.Stat(base + extension)
+		if err == nil && !info.IsDir() {
+			return base + extension, true
+		}
+	}
+	return base, false
+}
+
+func loadSettingsFile(path string) (*properties.Properties, error) {
+	format := ConfigFormatForPath(path)
+	if format == FormatProperties {
+		return properties.LoadFile(path, properties.UTF8)
+	}
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	document := map[string]any{}
+	switch format {
+	case FormatYAML:
+		err = yaml.Unmarshal(contents, &document)
+	case FormatTOML:
+		err = toml.Unmarshal(contents, &document)
+	case FormatJSON:
+		err = json.Unmarshal(contents, &document)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	settings := properties.NewProperties()
+	err = flattenSettings(settings, "", document)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return settings, nil
+}
+
+func flattenSettings(settings *properties.Properties, prefix string, document map[string]any) error {
+	names := make([]string, 0, len(document))
+	for name := range document {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		key := name
+		if len(prefix) > 0 {
+			key = prefix + "." + name
+		}
+		var err error
+		switch value := document[name].(type) {
+		case map[string]any:
+			err = flattenSettings(settings, key, value)
+		case []any:
+			elements := make([]string, len(value))
+			for i, element := range value {
+				elements[i] = fmt.Sprint(element)
+			}
+			_, _, err = settings.Set(key, strings.Join(elements, ","))
+		case nil:
+			_, _, err = settings.Set(key, "")
+		default:
+			_, _, err = settings.Set(key, fmt.Sprint(value))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSettingsFile writes settings to path in the format
+// implied by its extension.  An existing properties file is
+// edited in place so that its comments, blank lines and
+// ordering are kept.
+func writeSettingsFile(path string, settings *properties.Properties) error {
+	return writeSettings(path, settings, true)
+}
+
+// replaceSettingsFile writes settings to path, replacing any
+// existing file as a whole.
+func replaceSettingsFile(path string, settings *properties.Properties) error {
+	return writeSettings(path, settings, false)
+}
+
+func writeSettings(path string, settings *properties.Properties, keepLayout bool) error {
+	var contents bytes.Buffer
+	var err error
+	switch format := ConfigFormatForPath(path); format {
+	case FormatProperties:
+		existing, readErr := os.ReadFile(path)
+		if keepLayout && readErr == nil {
+			var updated string
+			updated, err = updatePropertiesText(string(existing), settings)
+			contents.WriteString(updated)
+		} else {
+			_, err = settings.WriteComment(&contents, "# ", properties.UTF8)
+		}
+	case FormatYAML:
+		encoder := yaml.NewEncoder(&contents)
+		encoder.SetIndent(2)
+		err = encoder.Encode(nestSettings(settings))
+	case FormatTOML:
+		err = toml.NewEncoder(&contents).Encode(nestSettings(settings))
+	case FormatJSON:
+		var encoded []byte
+		encoded, err = json.MarshalIndent(nestSettings(settings), "", "  ")
+		contents.Write(encoded)
+		contents.WriteString("\n")
+	}
+	if err != nil {
+		return err
+	}
+
+	tempFile, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tempFile.Name())
+	_, err = tempFile.Write(contents.Bytes())
+	if closeErr := tempFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if info, statErr := os.Stat(path); statErr == nil {
+		os.Chmod(tempFile.Name(), info.Mode().Perm())
+	}
+	return os.Rename(tempFile.Name(), path)
+}
+
+// updatePropertiesText returns the contents of a properties
+// file with the lines of the keys whose values differ from
+// settings rewritten, the lines of keys missing from settings
+// removed and the remaining keys of settings appended.  Every
+// other line is kept as written.
+func updatePropertiesText(contents string, settings *properties.Properties) (string, error) {
+	values := settings.Map()
+	loader := &properties.Loader{Encoding: properties.UTF8, DisableExpansion: true}
+	var builder strings.Builder
+	written := map[string]bool{}
+	lines := strings.SplitAfter(contents, "\n")
+	for i := 0; i < len(lines); {
+		start := i
+		i++
+		if !isPropertiesComment(lines[start]) {
+			for i < len(lines) && continuesPropertiesLine(lines[i-1]) {
+				i++
+			}
+		}
+		text := strings.Join(lines[start:i], "")
+		lineSettings, err := loader.LoadBytes([]byte(text))
+		if err != nil {
+			return "", err
+		}
+		if len(lineSettings.Keys()) == 0 {
+			builder.WriteString(text)
+			continue
+		}
+		key := lineSettings.Keys()[0]
+		value, found := values[key]
+		if !found || written[key] {
+			continue
+		}
+		written[key] = true
+		if previous, _ := lineSettings.Get(key); previous == value {
+			builder.WriteString(text)
+			continue
+		}
+		line, err := formatPropertiesLine(key, value)
+		if err != nil {
+			return "", err
+		}
+		builder.WriteString(line)
+		if !strings.HasSuffix(text, "\n") {
+			builder.WriteString("\n")
+		}
+	}
+	if builder.Len() > 0 && !strings.HasSuffix(builder.String(), "\n") {
+		builder.WriteString("\n")
+	}
+	for _, key := range settings.Keys() {
+		if written[key] {
+			continue
+		}
+		for _, comment := range settings.GetComments(key) {
+			builder.WriteString("# " + comment + "\n")
+		}
+		line, err := formatPropertiesLine(key, values[key])
+		if err != nil {
+			return "", err
+		}
+		builder.WriteString(line)
+	}
+	return builder.String(), nil
+}
+
+// isPropertiesComment reports whether line is a comment, which
+// unlike other lines can't be continued by a trailing backslash.
+func isPropertiesComment(line string) bool {
+	trimmed := strings.TrimLeft(line, " \t\f")
+	return strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "!")
+}
+
+// continuesPropertiesLine reports whether line ends with an odd
+// number of backslashes, continuing its value on the next line.
+func continuesPropertiesLine(line string) bool {
+	line = strings.TrimRight(line, "\r\n")
+	backslashes := len(line) - len(strings.TrimRight(line, "\\"))
+	return backslashes%2 == 1
+}
+
+// formatPropertiesLine returns the line for key and value as
+// written by properties.Write.
+func formatPropertiesLine(key string, value string) (string, error) {
+	line := properties.NewProperties()
+	line.DisableExpansion = true
+	if _, _, err := line.Set(key, value); err != nil {
+		return "", err
+	}
+	var contents strings.Builder
+	_, err := line.Write(&contents, properties.UTF8)
+	return contents.String(), err
+}
+
+// nestSettings converts dotted keys back into nested tables.
+// A key that has another key as its prefix, such as the
+// profile key and profile.<name>.<key> overrides, can't be
+// nested and is kept as a literal dotted key instead.
+func nestSettings(settings *properties.Properties) map[string]any {
+	keys := settings.Keys()
+	rawSettings := settings.Map()
+	keySet := map[string]bool{}
+	for _, key := range keys {
+		keySet[key] = true
+	}
+	document := map[string]any{}
+	for _, key := range keys {
+		typedValue := typedSettingValue(key, rawSettings[key])
+		parts := strings.Split(key, ".")
+		nestable := true
+		for i := 1; i < len(parts); i++ {
+			if keySet[strings.Join(parts[:i], ".")] {
+				nestable = false
+				break
+			}
+		}
+		if !nestable {
+			document[key] = typedValue
+			continue
+		}
+		table := document
+		for _, part := range parts[:len(parts)-1] {
+			child, found := table[part].(map[string]any)
+			if !found {
+				child = map[string]any{}
+				table[part] = child
+			}
+			table = child
+		}
+		table[parts[len(parts)-1]] = typedValue
+	}
+	return document
+}
+
+// typedSettingValue uses the registered schema to write lists,
+// booleans and integers as native values in structured formats.
+func typedSettingValue(key string, value string) any {
+	option, found := LookupConfigOption(key)
+	if !found {
+		return value
+	}
+	switch option.Type {
+	case ConfigTypeStringList:
+		return splitList(value)
+	case ConfigTypeBool:
+		if result, err := parseBool(value); err == nil {
+			return result
+		}
+	case ConfigTypeInt:
+		if result, err := parseInt(value); err == nil {
+			return result
+		}
+	}
+	return value
+}
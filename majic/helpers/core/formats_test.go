@@ -0,0 +1,99 @@
+/*
+Copyright © 2023 Mark Johnson
+*/
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteSettingsFileKeepsLayout(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		set      map[string]string
+		unset    []string
+		want     string
+	}{
+		{
+			"unchanged",
+			"# header\n\na = 1\n\nb = 2\n\n# footer\n\n",
+			nil, nil,
+			"# header\n\na = 1\n\nb = 2\n\n# footer\n\n",
+		},
+		{
+			"set",
+			"# header\n\na = 1\n\nb = 2\n\n# footer\n",
+			map[string]string{"a": "${MAJIC_HOME}/one"}, nil,
+			"# header\n\na = ${MAJIC_HOME}/one\n\nb = 2\n\n# footer\n",
+		},
+		{
+			"unset",
+			"# header\n\n# about a\na = 1\n\nb = 2\n\n# footer\n",
+			nil, []string{"a"},
+			"# header\n\n# about a\n\nb = 2\n\n# footer\n",
+		},
+		{
+			"new key after the footer",
+			"a = 1\n\n# footer\n",
+			map[string]string{"c": "3"}, nil,
+			"a = 1\n\n# footer\nc = 3\n",
+		},
+		{
+			"no final newline",
+			"a = 1",
+			map[string]string{"b": "2"}, nil,
+			"a = 1\nb = 2\n",
+		},
+		{
+			"other separators kept",
+			"a: 1\nb    2\nc=3\n",
+			map[string]string{"c": "three"}, nil,
+			"a: 1\nb    2\nc = three\n",
+		},
+		{
+			"continued value",
+			"a = one \\\n    two\n# footer \\\nb = 2\n",
+			map[string]string{"a": "one"}, nil,
+			"a = one\n# footer \\\nb = 2\n",
+		},
+		{
+			"continued value unchanged",
+			"a = one \\\n    two\n# footer\n",
+			map[string]string{"b": "2"}, nil,
+			"a = one \\\n    two\n# footer\nb = 2\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), AppConfigFile)
+			if err := os.WriteFile(path, []byte(test.contents), 0600); err != nil {
+				t.Fatal(err)
+			}
+			settings, err := loadSettingsFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for key, value := range test.set {
+				if _, _, err := settings.Set(key, value); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for _, key := range test.unset {
+				settings.Delete(key)
+			}
+			if err := writeSettingsFile(path, settings); err != nil {
+				t.Fatal(err)
+			}
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != test.want {
+				t.Errorf("writeSettingsFile() wrote\n%q\nwant\n%q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+/*
+Copyright © 2023 Mark Johnson
+*/
+package core
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/magiconair/properties"
+)
+
+var configFileOverride string
+
+// Home returns the majic home directory, which holds the
+// configuration file and the default plugins, input and
+// output directories.  MAJIC_HOME relocates the home from
+// its default of ~/.majic.
+func Home() (string, error) {
+	if homeDirPath, found := os.LookupEnv(EnvKeyHome); found && len(homeDirPath) > 0 {
+		return ExpandPath(homeDirPath)
+	}
+	userHomeDirPath, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(userHomeDirPath, AppConfigHome), nil
+}
+
+// isDefaultHome reports whether Home is ~/.majic, rather than
+// somewhere else selected by MAJIC_HOME.
+func isDefaultHome() bool {
+	homeDirPath, err := Home()
+	if err != nil {
+		return false
+	}
+	userHomeDirPath, err := os.UserHomeDir()
+	if err != nil {
+		return false
+	}
+	defaultHomeDirPath := filepath.Join(userHomeDirPath, AppConfigHome)
+	return filepath.Clean(homeDirPath) == defaultHomeDirPath || sameFile(homeDirPath, defaultHomeDirPath)
+}
+
+// SetConfigFile selects the configuration file to load in
+// place of the clirc file in the majic home.  It must be
+// called before the configuration is first accessed.
+func SetConfigFile(path string) {
+	configFileOverride = path
+}
+
+// configFileLocation returns the configuration file path and
+// whether it was explicitly requested via --config or
+// MAJIC_CONFIG rather than found in the majic home.  The home
+// may contain clirc in any supported format; a properties
+// file named clirc is used when none exists yet.
+func configFileLocation(homeDirPath string) (string, bool, error) {
+	path := configFileOverride
+	if len(path) == 0 {
+		path = os.Getenv(EnvKeyConfig)
+	}
+	if len(path) > 0 {
+		expandedPath, err := ExpandPath(path)
+		return expandedPath, true, err
+	}
+	path, _ = findConfigFile(filepath.Join(homeDirPath, AppConfigFile))
+	return path, false, nil
+}
+
+// GenerateDefaultConfigFile writes a configuration file with
+// the default core settings to path, creating its directory
+// as needed.  An existing file is only replaced when
+// overwrite is set.
+func GenerateDefaultConfigFile(path string, overwrite bool) error {
+	if _, err := os.Stat(path); err == nil && !overwrite {
+		return &os.PathError{Op: "create", Path: path, Err: os.ErrExist}
+	}
+	err := os.MkdirAll(filepath.Dir(path), 0750)
+	if err != nil {
+		return err
+	}
+	return replaceSettingsFile(path, defaultConfigFileSettings())
+}
+
+func defaultConfigFileSettings() *properties.Properties {
+	settings := properties.NewProperties()
+	settings.SetValue(ConfigKeyOutputDir, DefaultOutputDir)
+	settings.SetValue(ConfigKeyInputDir, DefaultInputDir)
+	settings.SetValue(ConfigKeyPluginsDir, DefaultPluginsDir)
+	settings.SetValue(ConfigKeyConfigVersion, LatestConfigVersion(CoreNamespace))
+	return settings
+}
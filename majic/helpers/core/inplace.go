@@ -0,0 +1,32 @@
+/*
+Copyright © 2023 Mark Johnson
+*/
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+const ConfigKeyInPlace string = "in_place"
+const ConfigKeyPreserveMtime string = "preserve_mtime"
+
+const FlagKeyInPlace string = "in-place"
+const FlagKeyPreserveMtime string = "preserve-mtime"
+
+// ParseInPlace interprets the value of the in_place key, which
+// is either a boolean or the suffix appended to the name of the
+// backup kept of each file replaced, as with --in-place=.bak.
+func ParseInPlace(value string) (enabled bool, backupSuffix string, err error) {
+	value = strings.TrimSpace(value)
+	if len(value) == 0 {
+		return false, "", nil
+	}
+	if enabled, err := parseBool(value); err == nil {
+		return enabled, "", nil
+	}
+	if strings.ContainsAny(value, `/\`) {
+		return false, "", fmt.Errorf("backup suffix must not contain a path separator: %s", value)
+	}
+	return true, value, nil
+}
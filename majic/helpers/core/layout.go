@@ -0,0 +1,36 @@
+/*
+Copyright © 2023 Mark Johnson
+*/
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+const ConfigKeyOutputLayout string = "output_layout"
+const FlagKeyOutputLayout string = "output-layout"
+
+// OutputLayout selects where the files generated while
+// processing a directory are written.  The mirror layout
+// reproduces the directories below the input directory in the
+// output directory, while the flat layout writes every file to
+// the output directory itself.  Without the output_layout key
+// the layout is mirror for processors that implement
+// file.RelativePathProcessor and flat for all others.
+type OutputLayout string
+
+const (
+	OutputLayoutMirror OutputLayout = "mirror"
+	OutputLayoutFlat   OutputLayout = "flat"
+)
+
+// ParseOutputLayout converts a layout name, such as the value
+// of the --output-layout flag, to an OutputLayout.
+func ParseOutputLayout(name string) (OutputLayout, error) {
+	switch layout := OutputLayout(strings.ToLower(name)); layout {
+	case OutputLayoutMirror, OutputLayoutFlat:
+		return layout, nil
+	}
+	return "", fmt.Errorf("unsupported output layout: %s", name)
+}
@@ -0,0 +1,214 @@
+/*
+Copyright © 2023 Mark Johnson
+*/
+package core
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+const ConfigKeyLogFormat string = "log_format"
+const FlagKeyLogFormat string = "log-format"
+
+// LogFormat selects the encoding of the diagnostics written
+// to stderr.
+type LogFormat string
+
+const (
+	LogFormatText   LogFormat = "text"
+	LogFormatLogfmt LogFormat = "logfmt"
+	LogFormatJSON   LogFormat = "json"
+)
+
+const DefaultLogFormat LogFormat = LogFormatText
+
+var logLevel = new(slog.LevelVar)
+var logFormat = DefaultLogFormat
+var logger = slog.New(&logHandler{})
+
+func init() {
+	logLevel.Set(slog.LevelWarn)
+}
+
+// Logger returns the logger used for diagnostics.  Results
+// belong on stdout via Output().NormalOutput; everything else
+// is logged to stderr so that command output can be piped.
+//
+// The level follows the logging-level settings:  warnings and
+// errors are always shown, info messages with --detailed and
+// debug messages with --verbose.
+func Logger() *slog.Logger {
+	return logger
+}
+
+// PluginLogger returns the logger for the named plugin, which
+// tags each message with the plugin's name.
+func PluginLogger(name string) *slog.Logger {
+	return logger.With("plugin", name)
+}
+
+// ParseLogFormat converts a format name, such as the value of
+// the log_format setting, to a LogFormat.
+func ParseLogFormat(name string) (LogFormat, error) {
+	switch format := LogFormat(strings.ToLower(name)); format {
+	case LogFormatText, LogFormatLogfmt, LogFormatJSON:
+		return format, nil
+	}
+	return "", fmt.Errorf("unsupported log format: %s", name)
+}
+
+// SetLogFormat changes the encoding of subsequent diagnostics,
+// including those of loggers already handed out.
+func SetLogFormat(format LogFormat) {
+	logFormat = format
+}
+
+func setLogLevel(detailed bool, verbose bool) {
+	switch {
+	case verbose:
+		logLevel.Set(slog.LevelDebug)
+	case detailed:
+		logLevel.Set(slog.LevelInfo)
+	default:
+		logLevel.Set(slog.LevelWarn)
+	}
+}
+
+// logHandler defers choosing the encoding until a record is
+// handled, as the format isn't known until the configuration
+// has been loaded, by which time loggers have been derived
+// from it with attributes and groups.  Records are written to
+// the console and, at its own level, to the log file.
+type logHandler struct {
+	derive []func(handler slog.Handler) slog.Handler
+}
+
+func (h *logHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= logLevel.Level() || (logFile != nil && level >= logFileLevel.Level())
+}
+
+func (h *logHandler) Handle(ctx context.Context, record slog.Record) error {
+	var err error
+	options := &slog.HandlerOptions{Level: slog.LevelDebug}
+	if record.Level >= logLevel.Level() {
+		logWriter := Output().Stderr()
+		var handler slog.Handler
+		switch logFormat {
+		case LogFormatLogfmt:
+			handler = slog.NewTextHandler(logWriter, options)
+		case LogFormatJSON:
+			handler = slog.NewJSONHandler(logWriter, options)
+		default:
+			handler = &textLogHandler{writer: logWriter}
+		}
+		err = h.derived(handler).Handle(ctx, record)
+	}
+	if logFile != nil && record.Level >= logFileLevel.Level() {
+		fileErr := h.derived(slog.NewTextHandler(logFile, options)).Handle(ctx, record.Clone())
+		if err == nil {
+			err = fileErr
+		}
+	}
+	return err
+}
+
+func (h *logHandler) derived(handler slog.Handler) slog.Handler {
+	for _, derive := range h.derive {
+		handler = derive(handler)
+	}
+	return handler
+}
+
+func (h *logHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(handler slog.Handler) slog.Handler {
+		return handler.WithAttrs(attrs)
+	})
+}
+
+func (h *logHandler) WithGroup(name string) slog.Handler {
+	return h.with(func(handler slog.Handler) slog.Handler {
+		return handler.WithGroup(name)
+	})
+}
+
+func (h *logHandler) with(derive func(handler slog.Handler) slog.Handler) slog.Handler {
+	return &logHandler{append(append([]func(slog.Handler) slog.Handler{}, h.derive...), derive)}
+}
+
+// textLogHandler writes the message much like the CLI always
+// has, prefixing warnings and errors, followed by any
+// attributes as key=value pairs.
+type textLogHandler struct {
+	writer io.Writer
+	attrs  []slog.Attr
+	group  string
+}
+
+func (h *textLogHandler) Enabled(_ context.Context, _ slog.Level) bool {
+	return true
+}
+
+func (h *textLogHandler) Handle(_ context.Context, record slog.Record) error {
+	var line strings.Builder
+	switch {
+	case record.Level >= slog.LevelError:
+		line.WriteString(appOutput().Stylize(h.writer, StyleError, "Error:") + " ")
+	case record.Level >= slog.LevelWarn:
+		line.WriteString(appOutput().Stylize(h.writer, StyleWarning, "Warning:") + " ")
+	}
+	line.WriteString(record.Message)
+	for _, attr := range h.attrs {
+		writeLogAttr(&line, "", attr)
+	}
+	record.Attrs(func(attr slog.Attr) bool {
+		writeLogAttr(&line, h.group, attr)
+		return true
+	})
+	line.WriteString("\n")
+	_, err := io.WriteString(h.writer, line.String())
+	return err
+}
+
+func (h *textLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	derived := *h
+	derived.attrs = append([]slog.Attr{}, h.attrs...)
+	for _, attr := range attrs {
+		if len(h.group) > 0 {
+			attr.Key = h.group + attr.Key
+		}
+		derived.attrs = append(derived.attrs, attr)
+	}
+	return &derived
+}
+
+func (h *textLogHandler) WithGroup(name string) slog.Handler {
+	derived := *h
+	derived.group = h.group + name + "."
+	return &derived
+}
+
+func writeLogAttr(line *strings.Builder, prefix string, attr slog.Attr) {
+	value := attr.Value.Resolve()
+	if value.Kind() == slog.KindGroup {
+		if len(attr.Key) > 0 {
+			prefix += attr.Key + "."
+		}
+		for _, groupAttr := range value.Group() {
+			writeLogAttr(line, prefix, groupAttr)
+		}
+		return
+	}
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+	text := value.String()
+	if len(text) == 0 || strings.ContainsAny(text, " \t\r\n\"=") {
+		text = strconv.Quote(text)
+	}
+	line.WriteString(" " + prefix + attr.Key + "=" + text)
+}
@@ -0,0 +1,181 @@
+/*
+Copyright © 2023 Mark Johnson
+*/
+package core
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const ConfigKeyLogFile string = "log_file"
+const ConfigKeyLogFileLevel string = "log_file_level"
+const ConfigKeyLogFileMaxSize string = "log_file_max_size"
+const ConfigKeyLogFileMaxFiles string = "log_file_max_files"
+const DefaultLogFileLevel string = "debug"
+const DefaultLogFileMaxSize int = 10
+const DefaultLogFileMaxFiles int = 5
+
+var logFile *rotatingFile
+var logFileLevel = new(slog.LevelVar)
+
+// ParseLogLevel converts a level name such as "debug" or
+// "warn" to a slog.Level.
+func ParseLogLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(name))
+	if err != nil {
+		return level, fmt.Errorf("unsupported log level: %s", name)
+	}
+	return level, nil
+}
+
+// LogFilePaths returns the path of the log file followed by
+// its rotated predecessors, newest first, or nil when no log
+// file is configured.
+func (config *AppConfig) LogFilePaths() ([]string, error) {
+	path, err := config.GetPath(ConfigKeyLogFile)
+	if err != nil || len(path) == 0 {
+		return nil, err
+	}
+	maxFiles, err := config.GetInt(ConfigKeyLogFileMaxFiles)
+	if err != nil {
+		return nil, err
+	}
+	paths := []string{path}
+	for i := 1; i < maxFiles; i++ {
+		paths = append(paths, rotatedLogFilePath(path, i))
+	}
+	return paths, nil
+}
+
+// openLogFile starts capturing diagnostics in the log file, if
+// one is configured, independently of the console level.
+func (config *AppConfig) openLogFile() error {
+	path, err := config.GetPath(ConfigKeyLogFile)
+	if err != nil || len(path) == 0 {
+		return err
+	}
+	levelName, err := config.GetString(ConfigKeyLogFileLevel)
+	if err != nil {
+		return err
+	}
+	level, err := ParseLogLevel(levelName)
+	if err != nil {
+		return &ConfigValueError{ConfigKeyLogFileLevel, levelName, "log level", err}
+	}
+	maxSize, err := config.GetInt(ConfigKeyLogFileMaxSize)
+	if err != nil {
+		return err
+	}
+	maxFiles, err := config.GetInt(ConfigKeyLogFileMaxFiles)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0750)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	logFileLevel.Set(level)
+	logFile = &rotatingFile{
+		path:     path,
+		file:     file,
+		size:     info.Size(),
+		maxSize:  int64(maxSize) * 1024 * 1024,
+		maxFiles: maxFiles,
+	}
+	Logger().Info("majic started", "args", strings.Join(redactArgs(os.Args[1:]), " "), "pid", os.Getpid())
+	return nil
+}
+
+// redactArgs hides the value following a secret key, as in
+// majic config set api_token <value>.
+func redactArgs(args []string) []string {
+	redacted := append([]string{}, args...)
+	for i := 1; i < len(redacted); i++ {
+		if !strings.HasPrefix(args[i-1], "-") && IsSecretKey(args[i-1]) {
+			redacted[i] = RedactValue(args[i-1], args[i])
+		}
+	}
+	return redacted
+}
+
+// CloseLogFile stops capturing messages in the log file, such
+// as while the log file itself is being displayed.
+func CloseLogFile() {
+	if logFile != nil {
+		logFile.mutex.Lock()
+		defer logFile.mutex.Unlock()
+		if logFile.file != nil {
+			logFile.file.Close()
+		}
+		logFile = nil
+	}
+}
+
+// rotatingFile is a log file that is renamed to path.1 once it
+// reaches maxSize, shifting the older files along and keeping
+// at most maxFiles in total.
+type rotatingFile struct {
+	mutex    sync.Mutex
+	path     string
+	file     *os.File
+	size     int64
+	maxSize  int64
+	maxFiles int
+}
+
+func (file *rotatingFile) Write(contents []byte) (int, error) {
+	file.mutex.Lock()
+	defer file.mutex.Unlock()
+	if file.file == nil {
+		return 0, os.ErrClosed
+	}
+	if file.maxSize > 0 && file.size > 0 && file.size+int64(len(contents)) > file.maxSize {
+		if err := file.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := file.file.Write(contents)
+	file.size += int64(n)
+	return n, err
+}
+
+func (file *rotatingFile) rotate() error {
+	file.file.Close()
+	if file.maxFiles > 1 {
+		os.Remove(rotatedLogFilePath(file.path, file.maxFiles-1))
+		for i := file.maxFiles - 2; i >= 1; i-- {
+			os.Rename(rotatedLogFilePath(file.path, i), rotatedLogFilePath(file.path, i+1))
+		}
+		os.Rename(file.path, rotatedLogFilePath(file.path, 1))
+	} else {
+		os.Remove(file.path)
+	}
+	file.size = 0
+	reopened, err := os.OpenFile(file.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		file.file = nil
+		return err
+	}
+	file.file = reopened
+	return nil
+}
+
+func rotatedLogFilePath(path string, index int) string {
+	return path + "." + strconv.Itoa(index)
+}
@@ -0,0 +1,267 @@
+/*
+Copyright © 2023 Mark Johnson
+*/
+package core
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/magiconair/properties"
+)
+
+const ConfigKeyConfigVersion string = "config_version"
+const migrationBackupTimeFormat string = "20060102-150405"
+
+// Migration upgrades the configuration file settings owned by
+// a namespace to Version.  Migrations for a namespace are
+// applied in order of Version, starting after the version
+// recorded in the configuration file.
+type Migration struct {
+	Namespace   string
+	Version     int
+	Description string
+	Apply       func(settings *MigrationSettings) error
+}
+
+// MigrationSettings gives migrations access to the settings
+// in the configuration file and records each change made so
+// that migrations can be previewed.
+type MigrationSettings struct {
+	settings *properties.Properties
+	changes  []string
+}
+
+func (settings *MigrationSettings) Get(key string) (string, bool) {
+	value, found := settings.settings.Map()[key]
+	return value, found
+}
+
+func (settings *MigrationSettings) Keys() []string {
+	return settings.settings.Keys()
+}
+
+func (settings *MigrationSettings) Set(key string, value string) error {
+	previous, found := settings.Get(key)
+	if found && previous == value {
+		return nil
+	}
+	_, _, err := settings.settings.Set(key, value)
+	if err != nil {
+		return err
+	}
+	if found {
+		settings.changes = append(settings.changes, fmt.Sprintf("set %s = %s (was %s)", key, RedactValue(key, value), RedactValue(key, previous)))
+	} else {
+		settings.changes = append(settings.changes, fmt.Sprintf("set %s = %s", key, RedactValue(key, value)))
+	}
+	return nil
+}
+
+func (settings *MigrationSettings) Delete(key string) {
+	if _, found := settings.Get(key); found {
+		settings.settings.Delete(key)
+		settings.changes = append(settings.changes, "unset "+key)
+	}
+}
+
+func (settings *MigrationSettings) Rename(oldKey string, newKey string) error {
+	value, found := settings.Get(oldKey)
+	if !found {
+		return nil
+	}
+	comments := settings.settings.GetComments(oldKey)
+	settings.settings.Delete(oldKey)
+	_, _, err := settings.settings.Set(newKey, value)
+	if err != nil {
+		return err
+	}
+	settings.settings.SetComments(newKey, comments)
+	settings.changes = append(settings.changes, "rename "+oldKey+" to "+newKey)
+	return nil
+}
+
+// MigrationResult describes a migration that was, or in a dry
+// run would be, applied to the configuration file.
+type MigrationResult struct {
+	Migration   Migration
+	FromVersion int
+	Changes     []string
+}
+
+var migrations = map[string][]Migration{}
+
+func init() {
+	RegisterMigration(Migration{
+		Namespace:   CoreNamespace,
+		Version:     1,
+		Description: "locate the default directories relative to ${MAJIC_HOME}",
+		Apply: func(settings *MigrationSettings) error {
+			// Paths below ~/.majic only mean the same as those
+			// below ${MAJIC_HOME} when the home hasn't moved.
+			if !isDefaultHome() {
+				return nil
+			}
+			keys := []string{ConfigKeyPluginsDir, ConfigKeyInputDir, ConfigKeyOutputDir}
+			defaults := []string{DefaultPluginsDir, DefaultInputDir, DefaultOutputDir}
+			for i, key := range keys {
+				defaultValue := defaults[i]
+				legacyValue := strings.Replace(defaultValue, "${MAJIC_HOME}", "${HOME}/"+AppConfigHome, 1)
+				if value, found := settings.Get(key); found && value == legacyValue {
+					if err := settings.Set(key, defaultValue); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	})
+}
+
+// RegisterMigration adds a migration for the settings owned by
+// a namespace.  Plugins typically register their migrations
+// when they are loaded.
+func RegisterMigration(migration Migration) error {
+	if len(migration.Namespace) == 0 || migration.Version < 1 || migration.Apply == nil {
+		return fmt.Errorf("invalid configuration migration %q for namespace %q", migration.Description, migration.Namespace)
+	}
+	for _, registered := range migrations[migration.Namespace] {
+		if registered.Version == migration.Version {
+			return fmt.Errorf("configuration migration %d already registered for namespace %s", migration.Version, migration.Namespace)
+		}
+	}
+	namespaceMigrations := append(migrations[migration.Namespace], migration)
+	sort.Slice(namespaceMigrations, func(i, j int) bool {
+		return namespaceMigrations[i].Version < namespaceMigrations[j].Version
+	})
+	migrations[migration.Namespace] = namespaceMigrations
+	return nil
+}
+
+// LatestConfigVersion returns the version the settings owned by
+// namespace are at once all registered migrations are applied.
+func LatestConfigVersion(namespace string) int {
+	namespaceMigrations := migrations[namespace]
+	if len(namespaceMigrations) == 0 {
+		return 0
+	}
+	return namespaceMigrations[len(namespaceMigrations)-1].Version
+}
+
+// ConfigVersionKey returns the key recording the version of the
+// settings owned by namespace.
+func ConfigVersionKey(namespace string) string {
+	return QualifiedConfigKey(namespace, ConfigKeyConfigVersion)
+}
+
+// PendingMigrations returns the migrations that have not been
+// applied to the configuration file.
+func (config *AppConfig) PendingMigrations() []Migration {
+	if _, err := os.Stat(config.configFilePath); err != nil {
+		return nil
+	}
+	var pending []Migration
+	for _, namespace := range migrationNamespaces() {
+		version := configVersion(config.savedSettings, namespace)
+		for _, migration := range migrations[namespace] {
+			if migration.Version > version {
+				pending = append(pending, migration)
+			}
+		}
+	}
+	return pending
+}
+
+// ApplyPendingMigrations applies the pending migrations to the
+// configuration file settings in memory, leaving the file
+// itself to be updated by Migrate.
+func (config *AppConfig) ApplyPendingMigrations() ([]MigrationResult, error) {
+	results, settings, err := config.migrateSettings()
+	if err == nil && settings != nil {
+		config.configFileSettings = settings
+	}
+	return results, err
+}
+
+// Migrate applies the pending migrations to the configuration
+// file, keeping a backup of the previous file.  With dryRun set
+// the changes are reported without modifying anything.
+func (config *AppConfig) Migrate(dryRun bool) ([]MigrationResult, error) {
+	results, settings, err := config.migrateSettings()
+	if err != nil || settings == nil || dryRun {
+		return results, err
+	}
+
+	backupPath := config.configFilePath + ".bak-" + time.Now().Format(migrationBackupTimeFormat)
+	contents, err := os.ReadFile(config.configFilePath)
+	if err == nil {
+		err = os.WriteFile(backupPath, contents, 0600)
+	}
+	if err != nil {
+		return results, fmt.Errorf("could not back up configuration file: %w", err)
+	}
+	Output().DetailedOutput("Configuration file backed up to " + backupPath)
+	err = writeSettingsFile(config.configFilePath, settings)
+	if err != nil {
+		return results, err
+	}
+	config.configFileSettings = settings
+	config.savedSettings = settings
+	return results, nil
+}
+
+// migrateSettings returns the configuration file settings with
+// the pending migrations applied, or nil if none are pending.
+func (config *AppConfig) migrateSettings() ([]MigrationResult, *properties.Properties, error) {
+	pending := config.PendingMigrations()
+	if len(pending) == 0 {
+		return nil, nil, nil
+	}
+	settings := &MigrationSettings{settings: properties.NewProperties()}
+	settings.settings.Merge(config.savedSettings)
+
+	var results []MigrationResult
+	for _, migration := range pending {
+		fromVersion := configVersion(settings.settings, migration.Namespace)
+		settings.changes = nil
+		err := migration.Apply(settings)
+		if err == nil {
+			err = settings.Set(ConfigVersionKey(migration.Namespace), strconv.Itoa(migration.Version))
+		}
+		if err != nil {
+			return results, nil, fmt.Errorf("configuration migration %s %d (%s): %w", migration.Namespace, migration.Version, migration.Description, err)
+		}
+		results = append(results, MigrationResult{migration, fromVersion, settings.changes})
+	}
+	return results, settings.settings, nil
+}
+
+func configVersion(settings *properties.Properties, namespace string) int {
+	value, found := settings.Get(ConfigVersionKey(namespace))
+	if !found {
+		return 0
+	}
+	version, err := parseInt(value)
+	if err != nil {
+		return 0
+	}
+	return version
+}
+
+func migrationNamespaces() []string {
+	namespaces := []string{}
+	for namespace := range migrations {
+		if namespace != CoreNamespace {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	sort.Strings(namespaces)
+	if _, found := migrations[CoreNamespace]; found {
+		namespaces = append([]string{CoreNamespace}, namespaces...)
+	}
+	return namespaces
+}
@@ -0,0 +1,113 @@
+/*
+Copyright © 2023 Mark Johnson
+*/
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/magiconair/properties"
+)
+
+// Project configuration files are discovered by walking up
+// from the working directory, allowing a repository to check
+// in its own input/output layout and processor settings.
+// Besides .majic.properties, .majic.yaml, .majic.toml and
+// .majic.json are recognized.
+const ProjectConfigFile string = ".majic.properties"
+
+// ProjectFiles returns the project configuration files merged
+// over the user configuration, nearest to the working
+// directory last.
+func (config *AppConfig) ProjectFiles() []string {
+	return config.projectFilePaths
+}
+
+// fileLayers returns the configuration file layers in order
+// of precedence.
+func (config *AppConfig) fileLayers() []*properties.Properties {
+	if config.projectConfigEnabled() {
+		return []*properties.Properties{config.projectSettings, config.configFileSettings}
+	}
+	return []*properties.Properties{config.configFileSettings}
+}
+
+// projectConfigEnabled consults only the layers that can't be
+// supplied by a project configuration file itself.
+func (config *AppConfig) projectConfigEnabled() bool {
+	value, found := config.flagSettings.Get(ConfigKeyNoProjectConfig)
+	if !found {
+		value, found = os.LookupEnv(EnvKey(ConfigKeyNoProjectConfig))
+	}
+	if !found {
+		value, found = config.configFileSettings.Get(ConfigKeyNoProjectConfig)
+	}
+	if !found {
+		return true
+	}
+	disabled, err := parseBool(value)
+	return err != nil || !disabled
+}
+
+func loadProjectConfigFiles(userConfigFilePath string) (*properties.Properties, []string) {
+	projectSettings := properties.NewProperties()
+	projectFilePaths := []string{}
+	workingDirPath, err := os.Getwd()
+	if err != nil {
+		Output().DetailedOutput("Skipping project configuration: " + err.Error())
+		return projectSettings, projectFilePaths
+	}
+	// The user's home directory holds the user configuration,
+	// even when MAJIC_HOME or --config points elsewhere, so its
+	// .majic/clirc is never treated as a project file.
+	userHomeDirPath, _ := os.UserHomeDir()
+	var candidates []string
+	for dirPath := workingDirPath; ; dirPath = filepath.Dir(dirPath) {
+		// Within a directory .majic.properties takes precedence
+		// over .majic/clirc, so it is listed first here as the
+		// candidates are merged in reverse order.
+		projectFilePath, found := findConfigFile(filepath.Join(dirPath, strings.TrimSuffix(ProjectConfigFile, filepath.Ext(ProjectConfigFile))))
+		if !found {
+			projectFilePath = filepath.Join(dirPath, ProjectConfigFile)
+		}
+		candidates = append(candidates, projectFilePath)
+		if userHomeDirPath == "" || !sameFile(dirPath, userHomeDirPath) {
+			homeFilePath, _ := findConfigFile(filepath.Join(dirPath, AppConfigHome, AppConfigFile))
+			candidates = append(candidates, homeFilePath)
+		}
+		if filepath.Dir(dirPath) == dirPath {
+			break
+		}
+	}
+	for i := len(candidates) - 1; i >= 0; i-- {
+		if sameFile(candidates[i], userConfigFilePath) {
+			continue
+		}
+		info, err := os.Stat(candidates[i])
+		if err != nil || info.IsDir() {
+			continue
+		}
+		settings, err := loadSettingsFile(candidates[i])
+		if err != nil {
+			Logger().Warn("skipping project configuration file "+candidates[i], "error", err)
+			continue
+		}
+		projectSettings.Merge(settings)
+		projectFilePaths = append(projectFilePaths, candidates[i])
+	}
+	return projectSettings, projectFilePaths
+}
+
+func sameFile(path string, otherPath string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	otherInfo, err := os.Stat(otherPath)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(info, otherInfo)
+}
@@ -0,0 +1,272 @@
+/*
+Copyright © 2023 Mark Johnson
+*/
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/magiconair/properties"
+)
+
+type ConfigType string
+
+const (
+	ConfigTypeString     ConfigType = "string"
+	ConfigTypeBool       ConfigType = "bool"
+	ConfigTypeInt        ConfigType = "int"
+	ConfigTypeDuration   ConfigType = "duration"
+	ConfigTypePath       ConfigType = "path"
+	ConfigTypeStringList ConfigType = "list"
+	ConfigTypeMap        ConfigType = "map"
+)
+
+// CoreNamespace is the namespace of the configuration keys
+// owned by majic itself.  Unlike plugin keys, core keys are
+// not prefixed with their namespace.
+const CoreNamespace string = "majic"
+
+// ConfigOption documents a single configuration key.
+type ConfigOption struct {
+	Key         string
+	Type        ConfigType
+	Default     string
+	Description string
+	// Validate optionally performs additional checks on
+	// values that are well-formed for Type.
+	Validate func(value string) error
+	// Secret values are redacted whenever they are displayed
+	// and are typically read with AppConfig.GetSecret.
+	Secret bool
+}
+
+// ConfigSchema declares the configuration keys owned by majic
+// or by a plugin.  Plugin keys are namespaced, so the key
+// "greeting" in the "myplugin" namespace is configured as
+// myplugin.greeting.
+type ConfigSchema struct {
+	Namespace   string
+	Description string
+	Options     []ConfigOption
+}
+
+// UnknownConfigKeyError reports a key in the configuration
+// file that isn't declared by any registered schema.
+type UnknownConfigKeyError struct {
+	Key string
+}
+
+func (e *UnknownConfigKeyError) Error() string {
+	return "unknown configuration key: " + e.Key
+}
+
+type registeredOption struct {
+	namespace string
+	option    ConfigOption
+}
+
+var configSchemas []ConfigSchema
+var configOptions = map[string]registeredOption{}
+var configDefaults = properties.NewProperties()
+
+func init() {
+	RegisterConfigSchema(ConfigSchema{
+		Namespace:   CoreNamespace,
+		Description: "majic CLI core settings",
+		Options: []ConfigOption{
+			{Key: ConfigKeyPluginsDir, Type: ConfigTypePath, Default: DefaultPluginsDir, Description: "directory scanned for plugin (*.so) files"},
+			{Key: ConfigKeyInputDir, Type: ConfigTypePath, Default: DefaultInputDir, Description: "default directory of files to process"},
+			{Key: ConfigKeyOutputDir, Type: ConfigTypePath, Default: DefaultOutputDir, Description: "directory that generated files are written to"},
+			{Key: FlagKeyDetailedOutput, Type: ConfigTypeBool, Default: strconv.FormatBool(DefaultDetailedOutput), Description: "show detailed output"},
+			{Key: FlagKeyVerboseOutput, Type: ConfigTypeBool, Default: strconv.FormatBool(DefaultVerboseOutput), Description: "show verbose output (i.e. everything)"},
+			{Key: ConfigKeyLogFormat, Type: ConfigTypeString, Default: string(DefaultLogFormat), Description: "encoding of the diagnostics written to stderr: text, logfmt or json", Validate: func(value string) error {
+				_, err := ParseLogFormat(value)
+				return err
+			}},
+			{Key: FlagKeyQuiet, Type: ConfigTypeBool, Default: "false", Description: "don't report the progress of long running operations"},
+			{Key: ConfigKeyNoColor, Type: ConfigTypeBool, Default: "false", Description: "don't style output written to a terminal, also disabled by NO_COLOR"},
+			{Key: ConfigKeyOutputFormat, Type: ConfigTypeString, Default: string(DefaultOutputFormat), Description: "format of command results written to stdout: text, json or yaml", Validate: func(value string) error {
+				_, err := ParseOutputFormat(value)
+				return err
+			}},
+			{Key: ConfigKeyLogFile, Type: ConfigTypePath, Description: "file that captures all diagnostics and results, e.g. ${MAJIC_HOME}/logs/majic.log"},
+			{Key: ConfigKeyLogFileLevel, Type: ConfigTypeString, Default: DefaultLogFileLevel, Description: "lowest level written to the log file: debug, info, warn or error", Validate: func(value string) error {
+				_, err := ParseLogLevel(value)
+				return err
+			}},
+			{Key: ConfigKeyLogFileMaxSize, Type: ConfigTypeInt, Default: strconv.Itoa(DefaultLogFileMaxSize), Description: "size in megabytes at which the log file is rotated"},
+			{Key: ConfigKeyLogFileMaxFiles, Type: ConfigTypeInt, Default: strconv.Itoa(DefaultLogFileMaxFiles), Description: "number of log files kept, including the current one"},
+			{Key: ConfigKeyProfile, Type: ConfigTypeString, Description: "name of the profile applied over the base settings"},
+			{Key: ConfigKeyNoProjectConfig, Type: ConfigTypeBool, Default: "false", Description: "ignore project configuration files found above the working directory"},
+			{Key: ConfigKeyOnError, Type: ConfigTypeString, Default: string(DefaultErrorPolicy), Description: "what to do when a file can't be processed: fail-fast or continue", Validate: func(value string) error {
+				_, err := ParseErrorPolicy(value)
+				return err
+			}},
+			{Key: ConfigKeyMaxErrors, Type: ConfigTypeInt, Default: "0", Description: "number of files that may fail before processing stops, 0 for no limit; implies on_error = continue", Validate: func(value string) error {
+				if maxErrors, _ := strconv.Atoi(value); maxErrors < 0 {
+					return fmt.Errorf("must not be negative: %s", value)
+				}
+				return nil
+			}},
+			{Key: ConfigKeyInclude, Type: ConfigTypeStringList, Description: "glob patterns, such as **/*.md, of the files to process relative to the directory being processed", Validate: validateGlobPatterns},
+			{Key: ConfigKeyExclude, Type: ConfigTypeStringList, Description: "glob patterns, such as **/node_modules, of the files and directories to leave out when processing a directory", Validate: validateGlobPatterns},
+			{Key: ConfigKeyOutputLayout, Type: ConfigTypeString, Description: "mirror to reproduce the input subdirectories in the output directory, or flat; unset, processors that implement RelativePathProcessor mirror", Validate: func(value string) error {
+				if len(value) == 0 {
+					return nil
+				}
+				_, err := ParseOutputLayout(value)
+				return err
+			}},
+			{Key: ConfigKeyJobs, Type: ConfigTypeInt, Default: "1", Description: "number of files processed at the same time, 0 for one per CPU", Validate: func(value string) error {
+				if jobs, _ := strconv.Atoi(value); jobs < 0 {
+					return fmt.Errorf("must not be negative: %s", value)
+				}
+				return nil
+			}},
+			{Key: ConfigKeyInPlace, Type: ConfigTypeString, Description: "replace each input file with its processed result instead of writing to the output directory; true, or the suffix of the backup kept of each file", Validate: func(value string) error {
+				_, _, err := ParseInPlace(value)
+				return err
+			}},
+			{Key: ConfigKeyPreserveMtime, Type: ConfigTypeBool, Default: "false", Description: "keep the modification time of files replaced in place"},
+			{Key: ConfigKeyDryRun, Type: ConfigTypeBool, Default: "false", Description: "run processors without writing any files, showing what each would change"},
+			{Key: ConfigKeyDiff, Type: ConfigTypeBool, Default: "false", Description: "show a unified diff of each file processed against its result"},
+			{Key: ConfigKeyErrorReport, Type: ConfigTypePath, Description: "file that a JSON report of the files that failed is written to"},
+		},
+	})
+}
+
+func validateGlobPatterns(value string) error {
+	for _, pattern := range splitList(value) {
+		if !doublestar.ValidatePattern(pattern) {
+			return fmt.Errorf("invalid glob pattern: %s", pattern)
+		}
+	}
+	return nil
+}
+
+// RegisterConfigSchema adds the options declared by schema
+// to the set of known configuration keys and makes their
+// defaults available as the lowest precedence layer.
+func RegisterConfigSchema(schema ConfigSchema) error {
+	if len(schema.Namespace) == 0 {
+		return fmt.Errorf("configuration schema has no namespace")
+	}
+	for _, registered := range configSchemas {
+		if registered.Namespace == schema.Namespace {
+			return fmt.Errorf("configuration schema already registered for namespace %s", schema.Namespace)
+		}
+	}
+	for _, option := range schema.Options {
+		key := QualifiedConfigKey(schema.Namespace, option.Key)
+		if existing, found := configOptions[key]; found {
+			return fmt.Errorf("configuration key %s is already registered by %s", key, existing.namespace)
+		}
+	}
+	for _, option := range schema.Options {
+		key := QualifiedConfigKey(schema.Namespace, option.Key)
+		configOptions[key] = registeredOption{schema.Namespace, option}
+		configDefaults.Set(key, option.Default)
+	}
+	configSchemas = append(configSchemas, schema)
+	return nil
+}
+
+// ConfigSchemas returns the registered schemas, with the core
+// schema first followed by the plugin schemas sorted by namespace.
+func ConfigSchemas() []ConfigSchema {
+	schemas := append([]ConfigSchema{}, configSchemas...)
+	sort.SliceStable(schemas, func(i, j int) bool {
+		if schemas[i].Namespace == CoreNamespace || schemas[j].Namespace == CoreNamespace {
+			return schemas[i].Namespace == CoreNamespace
+		}
+		return schemas[i].Namespace < schemas[j].Namespace
+	})
+	return schemas
+}
+
+// QualifiedConfigKey returns the key used in the configuration
+// file for an option declared in namespace.
+func QualifiedConfigKey(namespace string, key string) string {
+	if namespace == CoreNamespace {
+		return key
+	}
+	return namespace + "." + key
+}
+
+// LookupConfigOption returns the option declared for key.
+// Profile overrides of the form profile.<name>.<key> resolve
+// to the option declared for <key>, and keys nested under a
+// map option resolve to a string option.
+func LookupConfigOption(key string) (ConfigOption, bool) {
+	if profileKey, found := strings.CutPrefix(key, ProfileKeyPrefix); found {
+		if _, optionKey, found := strings.Cut(profileKey, "."); found {
+			key = optionKey
+		}
+	}
+	registered, found := configOptions[key]
+	if found {
+		return registered.option, true
+	}
+	if key == ConfigKeyConfigVersion || strings.HasSuffix(key, "."+ConfigKeyConfigVersion) {
+		return ConfigOption{Key: key, Type: ConfigTypeInt, Description: "version of the settings, maintained by majic config migrate"}, true
+	}
+	// Entries of a map option may be written as nested keys.
+	for prefix := key; strings.Contains(prefix, "."); {
+		prefix = prefix[:strings.LastIndex(prefix, ".")]
+		if registered, found := configOptions[prefix]; found && registered.option.Type == ConfigTypeMap {
+			return ConfigOption{Key: key, Type: ConfigTypeString, Description: registered.option.Description, Secret: registered.option.Secret}, true
+		}
+	}
+	return ConfigOption{}, false
+}
+
+// ValidateConfigValue checks that value is well-formed for
+// the type of option and passes the option's validator.
+func ValidateConfigValue(key string, option ConfigOption, value string) error {
+	var err error
+	switch option.Type {
+	case ConfigTypeBool:
+		_, err = parseBool(value)
+	case ConfigTypeInt:
+		_, err = parseInt(value)
+	case ConfigTypeDuration:
+		_, err = parseDuration(value)
+	case ConfigTypePath:
+		_, err = ExpandPath(value)
+	case ConfigTypeMap:
+		_, err = parseMap(value)
+	}
+	if err == nil && option.Validate != nil {
+		err = option.Validate(value)
+	}
+	if err != nil {
+		return &ConfigValueError{key, value, string(option.Type), err}
+	}
+	return nil
+}
+
+// Validate checks every key in the configuration files, including
+// profile overrides, against the registered schemas and returns
+// a problem for each malformed value or unknown key, and for
+// files holding secrets that other users can read.
+func (config *AppConfig) Validate() []error {
+	var problems []error
+	for _, settings := range config.fileLayers() {
+		for _, key := range settings.Keys() {
+			option, found := LookupConfigOption(key)
+			if !found {
+				problems = append(problems, &UnknownConfigKeyError{key})
+				continue
+			}
+			value, _ := settings.Get(key)
+			if err := ValidateConfigValue(key, option, value); err != nil {
+				problems = append(problems, err)
+			}
+		}
+	}
+	return append(problems, config.checkSecretPermissions()...)
+}
@@ -0,0 +1,173 @@
+/*
+Copyright © 2023 Mark Johnson
+*/
+package core
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+const RedactedValue string = "********"
+
+const (
+	SecretPrefixFile    string = "file:"
+	SecretPrefixEnv     string = "env:"
+	SecretPrefixCommand string = "cmd:"
+)
+
+// Keys that aren't declared by a schema are treated as secret
+// when their final segment contains one of these words.
+var secretKeyWords = []string{"password", "passwd", "secret", "token", "api_key", "apikey", "credential"}
+
+// InsecureConfigFileError reports a configuration file holding
+// secret values that other users are able to read.
+type InsecureConfigFileError struct {
+	Path string
+	Mode os.FileMode
+}
+
+func (e *InsecureConfigFileError) Error() string {
+	return fmt.Sprintf("configuration file %s contains secrets but is readable by other users (mode %04o), consider chmod 600", e.Path, e.Mode.Perm())
+}
+
+// IsSecretKey reports whether the value of key must be redacted
+// when displayed.
+func IsSecretKey(key string) bool {
+	if option, found := LookupConfigOption(key); found {
+		return option.Secret
+	}
+	name := strings.ToLower(key[strings.LastIndex(key, ".")+1:])
+	for _, word := range secretKeyWords {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactValue returns value, or a placeholder when key holds a
+// secret.  Secret references such as env:NAME only reveal where
+// the secret is kept and are displayed as-is.
+func RedactValue(key string, value string) string {
+	if len(value) == 0 || isSecretReference(value) || !IsSecretKey(key) {
+		return value
+	}
+	return RedactedValue
+}
+
+// DisplayValue returns the effective value of key suitable for
+// displaying to the user, with secrets redacted.
+func (config *AppConfig) DisplayValue(key string) string {
+	value, _, _ := config.Lookup(key)
+	return RedactValue(key, value)
+}
+
+// GetSecret returns the value of key, resolving secret
+// references on first use:
+//
+//	file:/path/to/token    contents of the file
+//	env:NAME               value of the environment variable
+//	cmd:pass show api      output of the command
+//
+// Values without one of these prefixes are returned as-is.
+func (config *AppConfig) GetSecret(key string) (string, error) {
+	if secret, found := config.secrets[key]; found {
+		return secret, nil
+	}
+	value, err := config.GetString(key)
+	if err != nil {
+		return "", err
+	}
+	secret, err := resolveSecret(value)
+	if err != nil {
+		return "", &ConfigValueError{key, value, "secret", err}
+	}
+	if config.secrets == nil {
+		config.secrets = map[string]string{}
+	}
+	config.secrets[key] = secret
+	return secret, nil
+}
+
+func isSecretReference(value string) bool {
+	for _, prefix := range []string{SecretPrefixFile, SecretPrefixEnv, SecretPrefixCommand} {
+		if strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func resolveSecret(value string) (string, error) {
+	if path, found := strings.CutPrefix(value, SecretPrefixFile); found {
+		path, err := ExpandPath(path)
+		if err != nil {
+			return "", err
+		}
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(contents), "\r\n"), nil
+	}
+	if name, found := strings.CutPrefix(value, SecretPrefixEnv); found {
+		secret, found := os.LookupEnv(strings.TrimSpace(name))
+		if !found {
+			return "", fmt.Errorf("environment variable %s is not set", strings.TrimSpace(name))
+		}
+		return secret, nil
+	}
+	if command, found := strings.CutPrefix(value, SecretPrefixCommand); found {
+		var secretCmd *exec.Cmd
+		if runtime.GOOS == "windows" {
+			secretCmd = exec.Command("cmd", "/C", command)
+		} else {
+			secretCmd = exec.Command("sh", "-c", command)
+		}
+		var stderr bytes.Buffer
+		secretCmd.Stderr = &stderr
+		output, err := secretCmd.Output()
+		if err != nil {
+			if message := strings.TrimSpace(stderr.String()); len(message) > 0 {
+				err = errors.New(err.Error() + ": " + message)
+			}
+			return "", err
+		}
+		return strings.TrimRight(string(output), "\r\n"), nil
+	}
+	return value, nil
+}
+
+// checkSecretPermissions reports configuration files that hold
+// literal secret values and are readable by other users.
+func (config *AppConfig) checkSecretPermissions() []error {
+	var problems []error
+	paths := append([]string{config.configFilePath}, config.projectFilePaths...)
+	for _, path := range paths {
+		settings, err := loadSettingsFile(path)
+		if err != nil {
+			continue
+		}
+		hasSecrets := false
+		for key, value := range settings.Map() {
+			if RedactValue(key, value) != value {
+				hasSecrets = true
+				break
+			}
+		}
+		if !hasSecrets {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err == nil && info.Mode().Perm()&0077 != 0 {
+			problems = append(problems, &InsecureConfigFileError{path, info.Mode()})
+		}
+	}
+	return problems
+}
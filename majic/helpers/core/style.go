@@ -0,0 +1,78 @@
+/*
+Copyright © 2023 Mark Johnson
+*/
+package core
+
+import (
+	"io"
+	"os"
+	"strings"
+)
+
+const ConfigKeyNoColor string = "no_color"
+const FlagKeyNoColor string = "no-color"
+
+// EnvKeyNoColor disables color when set to any non-empty
+// value, as described at https://no-color.org.
+const EnvKeyNoColor string = "NO_COLOR"
+
+// Style distinguishes kinds of messages when they are written
+// to a terminal.
+type Style int
+
+const (
+	StylePlain Style = iota
+	StyleSuccess
+	StyleWarning
+	StyleError
+	StyleHeading
+	StyleDim
+	StyleAdded
+	StyleRemoved
+	StyleHunk
+)
+
+var styleCodes = map[Style]string{
+	StyleSuccess: "32",
+	StyleWarning: "33",
+	StyleError:   "1;31",
+	StyleHeading: "1",
+	StyleDim:     "2",
+	StyleAdded:   "32",
+	StyleRemoved: "31",
+	StyleHunk:    "36",
+}
+
+// SetColor enables styling of output written to terminals.
+func (output *AppOutput) SetColor(color bool) {
+	output.color = color
+}
+
+// Stylize returns text decorated with style when writer is a
+// terminal and color is enabled, and text unchanged otherwise.
+func (output *AppOutput) Stylize(writer io.Writer, style Style, text string) string {
+	code, found := styleCodes[style]
+	if !found || !output.color || !IsTerminal(writer) {
+		return text
+	}
+	return "\033[" + code + "m" + text + "\033[0m"
+}
+
+// StyledOutput writes a result message to stdout like
+// NormalOutput, styled when stdout is a terminal.  The log file
+// gets the message unstyled.
+func (output *AppOutput) StyledOutput(style Style, message string) {
+	if output.Structured() {
+		output.NormalOutput(message)
+		return
+	}
+	if logFile != nil {
+		logFileResult(strings.TrimSuffix(message, "\n"))
+	}
+	output.print(output.Stylize(output.stdout, style, message))
+}
+
+func colorDisabledByEnvironment() bool {
+	value, found := os.LookupEnv(EnvKeyNoColor)
+	return found && len(value) > 0
+}
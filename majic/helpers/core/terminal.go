@@ -0,0 +1,23 @@
+/*
+Copyright © 2023 Mark Johnson
+*/
+package core
+
+import (
+	"io"
+	"os"
+)
+
+// IsTerminal reports whether writer is a file connected to a
+// terminal rather than redirected to a file or pipe.
+func IsTerminal(writer io.Writer) bool {
+	file, found := writer.(*os.File)
+	if !found {
+		return false
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,211 @@
+/*
+Copyright © 2023 Mark Johnson
+*/
+package core
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrConfigKeyNotFound = errors.New("configuration key not found")
+
+// ConfigValueError describes a configuration value that
+// could not be converted to the type requested by the caller.
+type ConfigValueError struct {
+	Key   string
+	Value string
+	Type  string
+	Err   error
+}
+
+func (e *ConfigValueError) Error() string {
+	return fmt.Sprintf("invalid %s value %q for configuration key %s: %v", e.Type, RedactValue(e.Key, e.Value), e.Key, e.Err)
+}
+
+func (e *ConfigValueError) Unwrap() error {
+	return e.Err
+}
+
+func (config *AppConfig) GetString(key string) (string, error) {
+	value, _, found := config.Lookup(key)
+	if !found {
+		return "", fmt.Errorf("%w: %s", ErrConfigKeyNotFound, key)
+	}
+	return value, nil
+}
+
+// GetBool accepts the values understood by strconv.ParseBool
+// as well as yes/no and on/off.
+func (config *AppConfig) GetBool(key string) (bool, error) {
+	value, err := config.GetString(key)
+	if err != nil {
+		return false, err
+	}
+	result, err := parseBool(value)
+	if err != nil {
+		return false, &ConfigValueError{key, value, string(ConfigTypeBool), err}
+	}
+	return result, nil
+}
+
+func (config *AppConfig) GetInt(key string) (int, error) {
+	value, err := config.GetString(key)
+	if err != nil {
+		return 0, err
+	}
+	result, err := parseInt(value)
+	if err != nil {
+		return 0, &ConfigValueError{key, value, string(ConfigTypeInt), err}
+	}
+	return result, nil
+}
+
+// GetDuration accepts values in the format understood by
+// time.ParseDuration, e.g. "90s" or "1h30m".
+func (config *AppConfig) GetDuration(key string) (time.Duration, error) {
+	value, err := config.GetString(key)
+	if err != nil {
+		return 0, err
+	}
+	result, err := parseDuration(value)
+	if err != nil {
+		return 0, &ConfigValueError{key, value, string(ConfigTypeDuration), err}
+	}
+	return result, nil
+}
+
+// GetPath returns the value of key as a cleaned file system
+// path with a leading ~ and any $VAR or ${VAR} references
+// expanded.
+func (config *AppConfig) GetPath(key string) (string, error) {
+	value, err := config.GetString(key)
+	if err != nil {
+		return "", err
+	}
+	result, err := ExpandPath(value)
+	if err != nil {
+		return "", &ConfigValueError{key, value, string(ConfigTypePath), err}
+	}
+	return result, nil
+}
+
+// GetStringList splits a comma separated value into its
+// trimmed, non-empty elements.
+func (config *AppConfig) GetStringList(key string) ([]string, error) {
+	value, err := config.GetString(key)
+	if err != nil {
+		return nil, err
+	}
+	return splitList(value), nil
+}
+
+// GetMap parses a comma separated list of name=value pairs.
+// When key itself isn't set, the map is assembled from keys of
+// the form key.<name>, as produced by nested tables in
+// structured configuration files.
+func (config *AppConfig) GetMap(key string) (map[string]string, error) {
+	value, err := config.GetString(key)
+	if errors.Is(err, ErrConfigKeyNotFound) {
+		result := map[string]string{}
+		for _, subKey := range config.Keys() {
+			if name, found := strings.CutPrefix(subKey, key+"."); found {
+				result[name], _ = config.GetString(subKey)
+			}
+		}
+		if len(result) > 0 {
+			return result, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	result, err := parseMap(value)
+	if err != nil {
+		return nil, &ConfigValueError{key, value, string(ConfigTypeMap), err}
+	}
+	return result, nil
+}
+
+// ExpandPath expands a leading ~ to the user's home directory
+// and substitutes $VAR and ${VAR} references from the
+// environment.  Referencing an undefined variable is an error.
+func ExpandPath(path string) (string, error) {
+	path = strings.TrimSpace(path)
+	if len(path) == 0 {
+		return "", nil
+	}
+	if path == "~" || strings.HasPrefix(path, "~"+string(filepath.Separator)) {
+		userHomeDirPath, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		path = userHomeDirPath + path[1:]
+	}
+	var undefined []string
+	path = os.Expand(path, func(name string) string {
+		value, found := os.LookupEnv(name)
+		if !found {
+			undefined = append(undefined, name)
+		}
+		return value
+	})
+	if len(undefined) > 0 {
+		return "", fmt.Errorf("undefined environment variable %s", strings.Join(undefined, ", "))
+	}
+	return filepath.Clean(path), nil
+}
+
+func parseBool(value string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "yes", "on":
+		return true, nil
+	case "no", "off":
+		return false, nil
+	}
+	result, err := strconv.ParseBool(strings.TrimSpace(value))
+	if err != nil {
+		return false, errors.Unwrap(err)
+	}
+	return result, nil
+}
+
+func parseInt(value string) (int, error) {
+	result, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return 0, errors.Unwrap(err)
+	}
+	return result, nil
+}
+
+func parseDuration(value string) (time.Duration, error) {
+	return time.ParseDuration(strings.TrimSpace(value))
+}
+
+func parseMap(value string) (map[string]string, error) {
+	result := map[string]string{}
+	for _, entry := range splitList(value) {
+		name, entryValue, found := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		if !found || len(name) == 0 {
+			return nil, fmt.Errorf("entry %q is not of the form name=value", entry)
+		}
+		result[name] = strings.TrimSpace(entryValue)
+	}
+	return result, nil
+}
+
+func splitList(value string) []string {
+	result := []string{}
+	for _, element := range strings.Split(value, ",") {
+		if element = strings.TrimSpace(element); len(element) > 0 {
+			result = append(result, element)
+		}
+	}
+	return result
+}
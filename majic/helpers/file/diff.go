@@ -0,0 +1,181 @@
+/*
+Copyright © 2023 Mark Johnson
+*/
+package file
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/shelterbelt/majic-cli/majic/helpers/core"
+)
+
+// diffContext is the number of unchanged lines shown around
+// the changes in a unified diff.
+const diffContext int = 3
+
+// maxDiffCells limits the size of the table used to find the
+// longest common subsequence of two files.  Beyond it the
+// changed lines are shown as removed and added as a whole.
+const maxDiffCells int = 1 << 22
+
+const noNewlineMarker string = `\ No newline at end of file`
+
+// diffLine is a line of a unified diff, marked ' ' when it is
+// in both files, '-' when it is removed and '+' when it is
+// added.
+type diffLine struct {
+	kind byte
+	text string
+}
+
+// unifiedDiff returns the changes from the contents of fromPath
+// to those of toPath in unified format, or an empty string if
+// they are the same.
+func unifiedDiff(fromPath string, toPath string, from string, to string) string {
+	if from == to {
+		return ""
+	}
+	lines := diffLines(splitLines(from), splitLines(to))
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "--- %s\n+++ %s\n", fromPath, toPath)
+	// fromLines[i] and toLines[i] count the lines of each file
+	// before lines[i].
+	fromLines := make([]int, len(lines)+1)
+	toLines := make([]int, len(lines)+1)
+	for i, line := range lines {
+		fromLines[i+1], toLines[i+1] = fromLines[i], toLines[i]
+		if line.kind != '+' {
+			fromLines[i+1]++
+		}
+		if line.kind != '-' {
+			toLines[i+1]++
+		}
+	}
+	for i := 0; i < len(lines); {
+		if lines[i].kind == ' ' {
+			i++
+			continue
+		}
+		// A hunk takes in the changes separated by no more than
+		// twice the context, so that hunks never overlap.
+		last := i
+		for j := i + 1; j < len(lines) && j-last <= 2*diffContext+1; j++ {
+			if lines[j].kind != ' ' {
+				last = j
+			}
+		}
+		start, end := max(0, i-diffContext), min(len(lines), last+1+diffContext)
+		fmt.Fprintf(&builder, "@@ -%s +%s @@\n", hunkRange(fromLines[start], fromLines[end]-fromLines[start]), hunkRange(toLines[start], toLines[end]-toLines[start]))
+		for _, line := range lines[start:end] {
+			builder.WriteByte(line.kind)
+			builder.WriteString(line.text)
+			if !strings.HasSuffix(line.text, "\n") {
+				builder.WriteString("\n" + noNewlineMarker + "\n")
+			}
+		}
+		i = end
+	}
+	return builder.String()
+}
+
+// hunkRange formats the start line and number of lines of a
+// hunk as in GNU diff.
+func hunkRange(line int, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", line)
+	} else if count == 1 {
+		return fmt.Sprintf("%d", line+1)
+	}
+	return fmt.Sprintf("%d,%d", line+1, count)
+}
+
+// splitLines splits contents into lines, keeping the line
+// endings so that a missing newline at the end is a change.
+func splitLines(contents string) []string {
+	lines := strings.SplitAfter(contents, "\n")
+	if len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines returns the lines of from and to, in the order of
+// a unified diff, with the lines they have in common found
+// from the longest common subsequence between their first and
+// last differences.
+func diffLines(from []string, to []string) []diffLine {
+	prefix := 0
+	for prefix < len(from) && prefix < len(to) && from[prefix] == to[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(from)-prefix && suffix < len(to)-prefix && from[len(from)-1-suffix] == to[len(to)-1-suffix] {
+		suffix++
+	}
+	var lines []diffLine
+	for _, text := range from[:prefix] {
+		lines = append(lines, diffLine{' ', text})
+	}
+	common := from[len(from)-suffix:]
+	from, to = from[prefix:len(from)-suffix], to[prefix:len(to)-suffix]
+	if len(from)*len(to) > maxDiffCells {
+		for _, text := range from {
+			lines = append(lines, diffLine{'-', text})
+		}
+		for _, text := range to {
+			lines = append(lines, diffLine{'+', text})
+		}
+	} else {
+		// subsequence[i*width+j] is the length of the longest common
+		// subsequence of from[i:] and to[j:].
+		width := len(to) + 1
+		subsequence := make([]int32, (len(from)+1)*width)
+		for i := len(from) - 1; i >= 0; i-- {
+			for j := len(to) - 1; j >= 0; j-- {
+				if from[i] == to[j] {
+					subsequence[i*width+j] = subsequence[(i+1)*width+j+1] + 1
+				} else {
+					subsequence[i*width+j] = max(subsequence[(i+1)*width+j], subsequence[i*width+j+1])
+				}
+			}
+		}
+		i, j := 0, 0
+		for i < len(from) || j < len(to) {
+			if i < len(from) && j < len(to) && from[i] == to[j] {
+				lines = append(lines, diffLine{' ', from[i]})
+				i++
+				j++
+			} else if j == len(to) || (i < len(from) && subsequence[(i+1)*width+j] >= subsequence[i*width+j+1]) {
+				lines = append(lines, diffLine{'-', from[i]})
+				i++
+			} else {
+				lines = append(lines, diffLine{'+', to[j]})
+				j++
+			}
+		}
+	}
+	for _, text := range common {
+		lines = append(lines, diffLine{' ', text})
+	}
+	return lines
+}
+
+// printDiff writes a unified diff to stdout, colored when
+// stdout is a terminal.
+func printDiff(diff string) {
+	for i, line := range strings.Split(strings.TrimSuffix(diff, "\n"), "\n") {
+		style := core.StylePlain
+		switch {
+		case i < 2:
+			style = core.StyleHeading
+		case strings.HasPrefix(line, "@@"):
+			style = core.StyleHunk
+		case strings.HasPrefix(line, "+"):
+			style = core.StyleAdded
+		case strings.HasPrefix(line, "-"):
+			style = core.StyleRemoved
+		}
+		core.Output().StyledOutput(style, line)
+	}
+}
@@ -0,0 +1,74 @@
+/*
+Copyright © 2023 Mark Johnson
+*/
+package file
+
+import "testing"
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		to   string
+		want string
+	}{
+		{"identical", "a\nb\n", "a\nb\n", ""},
+		{"both empty", "", "", ""},
+		{"from empty", "", "a\n", "@@ -0,0 +1 @@\n+a\n"},
+		{"to empty", "a\n", "", "@@ -1 +0,0 @@\n-a\n"},
+		{"newline added", "a\nb", "a\nb\n", "@@ -1,2 +1,2 @@\n a\n-b\n" + noNewlineMarker + "\n+b\n"},
+		{"newline removed", "a\nb\n", "a\nb", "@@ -1,2 +1,2 @@\n a\n-b\n+b\n" + noNewlineMarker + "\n"},
+		{"no newline either side", "a", "b", "@@ -1 +1 @@\n-a\n" + noNewlineMarker + "\n+b\n" + noNewlineMarker + "\n"},
+		{"line added", "1\n2\n3\n4\n", "1\n2\nx\n3\n4\n", "@@ -1,4 +1,5 @@\n 1\n 2\n+x\n 3\n 4\n"},
+		{"line removed", "1\n2\n3\n", "1\n3\n", "@@ -1,3 +1,2 @@\n 1\n-2\n 3\n"},
+		{
+			"context",
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			"1\n2\n3\n4\nfive\n6\n7\n8\n9\n10\n",
+			"@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		{
+			"merged hunks",
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			"one\n2\n3\n4\n5\n6\n7\neight\n9\n10\n",
+			"@@ -1,10 +1,10 @@\n-1\n+one\n 2\n 3\n 4\n 5\n 6\n 7\n-8\n+eight\n 9\n 10\n",
+		},
+		{
+			"separate hunks",
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n16\n",
+			"one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\nsixteen\n",
+			"@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n@@ -13,4 +13,4 @@\n 13\n 14\n 15\n-16\n+sixteen\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			want := test.want
+			if len(want) > 0 {
+				want = "--- a\n+++ b\n" + want
+			}
+			if got := unifiedDiff("a", "b", test.from, test.to); got != want {
+				t.Errorf("unifiedDiff(%q, %q) =\n%s\nwant\n%s", test.from, test.to, got, want)
+			}
+		})
+	}
+}
+
+func TestHunkRange(t *testing.T) {
+	tests := []struct {
+		line  int
+		count int
+		want  string
+	}{
+		{0, 0, "0,0"},
+		{3, 0, "3,0"},
+		{0, 1, "1"},
+		{4, 1, "5"},
+		{0, 3, "1,3"},
+		{12, 4, "13,4"},
+	}
+	for _, test := range tests {
+		if got := hunkRange(test.line, test.count); got != test.want {
+			t.Errorf("hunkRange(%d, %d) = %q, want %q", test.line, test.count, got, test.want)
+		}
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/shelterbelt/majic-cli/majic/helpers/core"
@@ -23,61 +24,198 @@ type FileProcessor interface {
 	Initialize(flags *pflag.FlagSet)
 	ShouldProcessFile(fileName string) bool
 	UseGeneratedFileNames() bool
+	// PreprocessNewTargetFile is passed a temporary file when
+	// processing with several jobs, in place or as a dry run,
+	// which is renamed to the target file name, or discarded,
+	// once processing is done.
 	PreprocessNewTargetFile(file *os.File)
 	TargetFileName() string
 	ProcessLine(input string) string
 	Reset()
 }
 
-func ProcessPath(inputIdentifier string, processor FileProcessor) {
-	outputDirPath := core.Config().GetD(core.ConfigKeyOutputDir, core.DefaultOutputDir).(string)
+// ProcessPath processes a file, or every file in a directory
+// accepted by the processor, writing the results to the output
+// directory or, with the in_place key, over the files processed.
+// With the dry_run key nothing is written.
+func ProcessPath(inputIdentifier string, processor FileProcessor) error {
 	info, err := os.Stat(inputIdentifier)
-	if err != nil && !os.IsExist(err) {
-		core.Output().NormalOutput("Item " + inputIdentifier + " does not exist.")
-	} else {
-		core.HandleError(err)
-		if info.IsDir() {
-			ProcessDirectory(inputIdentifier, outputDirPath, processor)
-		} else {
-			ProcessFile(inputIdentifier, outputDirPath, processor)
+	if err != nil {
+		return core.PathError(inputIdentifier, err)
+	}
+	inPlace, err := startInPlace()
+	if err != nil {
+		return err
+	}
+	preview, err := startPreview()
+	if err != nil {
+		return err
+	}
+	currentInPlace = inPlace
+	currentPreview = preview
+	defer func() {
+		currentPreview = nil
+		currentInPlace = nil
+	}()
+	outputDirPath, err := core.Config().GetPath(core.ConfigKeyOutputDir)
+	if err != nil {
+		return err
+	}
+	if inPlace != nil {
+		if err = checkOutputDir(inputIdentifier, info.IsDir(), outputDirPath); err != nil {
+			return err
+		}
+		outputDirPath = ""
+	} else if !preview.isDryRun() {
+		if outputDirPath, err = CreateOutputDir(); err != nil {
+			return err
 		}
 	}
+	if info.IsDir() {
+		return ProcessDirectory(inputIdentifier, outputDirPath, processor)
+	}
+	return ProcessFile(inputIdentifier, outputDirPath, processor)
 }
 
-func ProcessDirectory(sourceDirPath string, outputDirPath string, processor FileProcessor) {
-	sourceDir, err := os.Open(sourceDirPath)
-	core.HandleError(err)
-	defer sourceDir.Close()
-	files, err := sourceDir.Readdirnames(-1)
-	core.HandleError(err)
+// ProcessDirectory processes every file in a directory and its
+// subdirectories that is accepted by the processor.  Files that
+// can't be processed either stop processing or are collected
+// in a summary, as selected by the on_error and max_errors keys.
+func ProcessDirectory(sourceDirPath string, outputDirPath string, processor FileProcessor) (err error) {
+	if currentSummary == nil {
+		summary, summaryErr := startSummary()
+		if summaryErr != nil {
+			return summaryErr
+		}
+		jobs, jobsErr := configuredJobs()
+		if jobsErr != nil {
+			return jobsErr
+		}
+		layout, layoutErr := startLayout(sourceDirPath, processor)
+		if layoutErr != nil {
+			return layoutErr
+		}
+		filter, filterErr := startFilter(sourceDirPath)
+		if filterErr != nil {
+			return filterErr
+		}
+		currentSummary = summary
+		currentLayout = layout
+		currentFilter = filter
+		currentProgress = startProgress(sourceDirPath, processor)
+		defer func() {
+			currentProgress.finish()
+			currentProgress = nil
+			currentFilter = nil
+			currentLayout = nil
+			currentSummary = nil
+			err = summary.finish(sourceDirPath, err)
+		}()
+		if jobs > 1 {
+			if cloneable, found := processor.(CloneableFileProcessor); found {
+				return processDirectoryConcurrently(sourceDirPath, outputDirPath, cloneable, jobs)
+			}
+			core.Logger().Warn("processor doesn't implement CloneableFileProcessor, processing files one at a time", "jobs", jobs)
+		}
+	}
+	files, err := readDirNames(sourceDirPath)
+	if err != nil {
+		return err
+	}
 	for i := 0; i < len(files); i++ {
 		core.Output().DetailedOutput(files[i])
 		sourceFilePath := filepath.Join(sourceDirPath, files[i])
 		info, err := os.Stat(sourceFilePath)
-		if err != nil && !errors.Is(err, os.ErrExist) {
-			core.Output().NormalOutput("File " + sourceFilePath + " does not exist.")
+		if errors.Is(err, os.ErrNotExist) {
+			// Removed since the directory was read.
+			emitResult(FileResult{Status: FileStatusMissing, InputPath: sourceFilePath})
+			continue
+		} else if err != nil {
+			err = core.PathError(sourceFilePath, err)
+		} else if currentFilter.excludes(sourceFilePath, info.IsDir()) {
+			core.Output().VerboseOutput("Excluding: " + sourceFilePath)
+		} else if info.IsDir() {
+			err = ProcessDirectory(sourceFilePath, outputDirPath, processor)
+		} else if processor.ShouldProcessFile(files[i]) && currentInPlace.claim(sourceFilePath) {
+			err = ProcessFile(sourceFilePath, outputDirPath, processor)
 		} else {
-			core.HandleError(err)
-			if info.IsDir() {
-				ProcessDirectory(sourceFilePath, outputDirPath, processor)
-			} else {
-				if processor.ShouldProcessFile(files[i]) {
-					ProcessFile(sourceFilePath, outputDirPath, processor)
-				} else {
-					core.Output().NormalOutput("Skipping: " + files[i])
-				}
+			emitResult(FileResult{Status: FileStatusSkipped, InputPath: sourceFilePath})
+		}
+		if err != nil {
+			if err = currentSummary.fail(sourceFilePath, err); err != nil {
+				return err
 			}
 		}
 	}
+	return nil
 }
 
-func ProcessFile(filePath string, outputDirPath string, processor FileProcessor) {
+// readDirNames returns the names of the entries in dirPath in
+// the order that they are processed.
+func readDirNames(dirPath string) ([]string, error) {
+	dir, err := os.Open(dirPath)
+	if err != nil {
+		return nil, core.PathError(dirPath, err)
+	}
+	defer dir.Close()
+	names, err := dir.Readdirnames(-1)
+	if err != nil {
+		return nil, core.PathError(dirPath, err)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func ProcessFile(filePath string, outputDirPath string, processor FileProcessor) error {
+	targetFileName := ""
+	result, err := processFile(filePath, processor, func(name string) (*os.File, error) {
+		targetFileName = name
+		if currentPreview.isDryRun() {
+			return createTempFile(os.TempDir())
+		} else if currentInPlace != nil {
+			return createTempFile(currentInPlace.tempDirPath(filePath))
+		}
+		targetFilePath, err := currentLayout.targetFilePath(outputDirPath, filePath, targetFileName)
+		if err != nil {
+			return nil, err
+		}
+		targetFile, err := CreateTargetFile(targetFilePath)
+		if errors.Is(err, os.ErrPermission) {
+			return nil, core.PathError(targetFilePath, err)
+		}
+		return targetFile, err
+	}, core.Output().VerboseOutput)
+	if err == nil {
+		err = processorError(filePath, deliverResult(&result, filePath, outputDirPath, targetFileName, false))
+	}
+	if err != nil {
+		if (currentPreview.isDryRun() || currentInPlace != nil) && len(result.OutputPath) > 0 {
+			os.Remove(result.OutputPath)
+		}
+		return err
+	}
+	emitResult(result)
+	currentProgress.fileDone(result.BytesRead)
+	return nil
+}
+
+// processFile passes each line of filePath through processor,
+// writing the processed lines to the file returned by
+// createTarget for the target file name, and to logLine.  The
+// result's OutputPath is set as soon as the target file is
+// created, so that it is known even if processing fails.
+func processFile(filePath string, processor FileProcessor, createTarget func(targetFileName string) (*os.File, error), logLine func(line string)) (FileResult, error) {
+	result := FileResult{Status: FileStatusProcessed, InputPath: filePath}
 	file, err := os.Open(filePath)
-	core.HandleError(err)
+	if err != nil {
+		return result, core.PathError(filePath, err)
+	}
 	defer file.Close()
-	contentsScanner := bufio.NewScanner(file)
+	source := &countingReader{reader: file}
+	contentsScanner := bufio.NewScanner(source)
 	var targetFile *os.File
 
+	currentLayout.setRelativePath(processor, filePath)
 	targetFileName := filepath.Base(filePath)
 	if processor.UseGeneratedFileNames() {
 		targetFileName = processor.TargetFileName()
@@ -93,28 +231,58 @@ func ProcessFile(filePath string, outputDirPath string, processor FileProcessor)
 		if targetFile == nil {
 
 			if len(targetFileName) > 0 {
-				targetFile, err = CreateTargetFile(filepath.Join(outputDirPath, targetFileName))
-				core.HandleError(err)
+				targetFile, err = createTarget(targetFileName)
+				if err != nil {
+					return result, processorError(filePath, err)
+				}
 				processor.PreprocessNewTargetFile(targetFile)
 				defer targetFile.Close()
+				result.OutputPath = targetFile.Name()
+			}
+		}
+
+		logLine(processedLine)
+		if targetFile != nil {
+			written, err := targetFile.WriteString(processedLine)
+			result.BytesWritten += int64(written)
+			if err != nil {
+				return result, &core.ProcessorError{Path: filePath, Err: err}
 			}
 		}
+	}
+	if err := contentsScanner.Err(); err != nil {
+		return result, &core.ProcessorError{Path: filePath, Err: err}
+	}
+	result.BytesRead = source.count
+	return result, nil
+}
 
-		core.Output().VerboseOutput(processedLine)
-		targetFile.WriteString(processedLine)
+// processorError reports that filePath couldn't be processed
+// because of err, unless err is a permission error, which
+// has an exit code of its own.
+func processorError(filePath string, err error) error {
+	var permissionError *core.PermissionError
+	if err == nil || errors.As(err, &permissionError) {
+		return err
 	}
+	return &core.ProcessorError{Path: filePath, Err: err}
 }
 
 func CreateTargetFile(targetFilePath string) (*os.File, error) {
+	file, err := os.Create(uniqueFilePath(targetFilePath))
+	return file, err
+}
+
+// uniqueFilePath returns targetFilePath, or the first of
+// "name 1.ext", "name 2.ext" and so on that doesn't exist yet.
+func uniqueFilePath(targetFilePath string) string {
 	info, _ := os.Stat(targetFilePath)
 	desiredFilePath := targetFilePath
 	for i := 0; info != nil; i++ {
 		targetFilePath = convertStringToUniqueFileName(desiredFilePath, i)
 		info, _ = os.Stat(targetFilePath)
 	}
-
-	file, err := os.Create(targetFilePath)
-	return file, err
+	return targetFilePath
 }
 
 func CreateTargetCopyOfInputFile(outputDirPath string, sourceFilePath string) (*os.File, error) {
@@ -149,13 +317,16 @@ func convertStringToUniqueFileName(name string, index int) string {
 	return name
 }
 
-func CreateOutputDir() string {
-	outputPath := core.Config().GetD(core.ConfigKeyOutputDir, core.DefaultOutputDir).(string)
-	err := os.Mkdir(outputPath, 0750)
+func CreateOutputDir() (string, error) {
+	outputPath, err := core.Config().GetPath(core.ConfigKeyOutputDir)
+	if err != nil {
+		return "", err
+	}
+	err = os.MkdirAll(outputPath, 0750)
 	if err != nil && !errors.Is(err, os.ErrExist) {
-		core.HandleError(err)
+		return "", core.PathError(outputPath, err)
 	}
-	return outputPath
+	return outputPath, nil
 }
 
 // From: https://github.com/mactsouk/opensource.com/blob/master/cp3.go
@@ -0,0 +1,188 @@
+/*
+Copyright © 2023 Mark Johnson
+*/
+package file
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/shelterbelt/majic-cli/majic/helpers/core"
+)
+
+// IgnoreFileName is the name of the files, written in gitignore
+// syntax, that list the files and directories below them that
+// are left out when processing a directory.
+const IgnoreFileName string = ".majicignore"
+
+// ignoreRule is a pattern read from an ignore file.
+type ignoreRule struct {
+	// dirPath is the directory holding the ignore file,
+	// relative to the directory being processed.
+	dirPath string
+	pattern string
+	negate  bool
+	dirOnly bool
+}
+
+// filter selects the files and directories visited while
+// processing a directory, from the include and exclude keys
+// and the ignore files found in the tree.  Paths are matched
+// relative to the directory being processed, using forward
+// slashes.
+type filter struct {
+	sourceDirPath string
+	include       []string
+	exclude       []string
+	rules         map[string][]ignoreRule
+}
+
+var currentFilter *filter
+
+func startFilter(sourceDirPath string) (*filter, error) {
+	include, err := globPatterns(core.ConfigKeyInclude)
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := globPatterns(core.ConfigKeyExclude)
+	if err != nil {
+		return nil, err
+	}
+	return &filter{sourceDirPath, include, exclude, map[string][]ignoreRule{}}, nil
+}
+
+func globPatterns(key string) ([]string, error) {
+	patterns, err := core.Config().GetStringList(key)
+	if err != nil {
+		return nil, err
+	}
+	for _, pattern := range patterns {
+		if !doublestar.ValidatePattern(pattern) {
+			return nil, &core.ConfigValueError{Key: key, Value: pattern, Type: string(core.ConfigTypeStringList), Err: errors.New("invalid glob pattern")}
+		}
+	}
+	return patterns, nil
+}
+
+// excludes reports whether filePath, a file or a directory
+// found while processing the directory, is left out.  The
+// contents of excluded directories are never visited, and
+// ignore files themselves are never processed.
+func (filter *filter) excludes(filePath string, isDir bool) bool {
+	if filter == nil {
+		return false
+	}
+	relativePath, err := filepath.Rel(filter.sourceDirPath, filePath)
+	if err != nil {
+		return false
+	}
+	relativePath = filepath.ToSlash(relativePath)
+	if !isDir && path.Base(relativePath) == IgnoreFileName {
+		return true
+	}
+	if filter.ignored(relativePath, isDir) {
+		return true
+	}
+	for _, pattern := range filter.exclude {
+		if match, _ := doublestar.Match(pattern, relativePath); match {
+			return true
+		}
+	}
+	if !isDir && len(filter.include) > 0 {
+		for _, pattern := range filter.include {
+			if match, _ := doublestar.Match(pattern, relativePath); match {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// ignored applies the ignore files in the directories above
+// relativePath, where the last matching rule decides.
+func (filter *filter) ignored(relativePath string, isDir bool) bool {
+	ignored := false
+	for _, rule := range filter.ignoreRules(path.Dir(relativePath)) {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		rulePath := relativePath
+		if rule.dirPath != "." {
+			rulePath = strings.TrimPrefix(relativePath, rule.dirPath+"/")
+		}
+		if match, _ := doublestar.Match(rule.pattern, rulePath); match {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// ignoreRules returns the rules that apply to the entries of
+// dirPath: those of its parent directories followed by those
+// of its own ignore file.
+func (filter *filter) ignoreRules(dirPath string) []ignoreRule {
+	if rules, found := filter.rules[dirPath]; found {
+		return rules
+	}
+	var rules []ignoreRule
+	if dirPath != "." {
+		rules = append(rules, filter.ignoreRules(path.Dir(dirPath))...)
+	}
+	ignoreFilePath := filepath.Join(filter.sourceDirPath, filepath.FromSlash(dirPath), IgnoreFileName)
+	fileRules, err := readIgnoreFile(ignoreFilePath, dirPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		core.Logger().Warn("could not read "+IgnoreFileName, "path", ignoreFilePath, "error", err)
+	}
+	rules = append(rules, fileRules...)
+	filter.rules[dirPath] = rules
+	return rules
+}
+
+// readIgnoreFile reads the rules from an ignore file in
+// dirPath.  As in gitignore, a pattern without a slash matches
+// at any depth, a leading slash anchors a pattern to dirPath,
+// a trailing slash only matches directories and a leading !
+// includes entries excluded by an earlier pattern.
+func readIgnoreFile(ignoreFilePath string, dirPath string) ([]ignoreRule, error) {
+	file, err := os.Open(ignoreFilePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule := ignoreRule{dirPath: dirPath}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		}
+		line = strings.TrimPrefix(line, "\\")
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		if strings.Contains(line, "/") {
+			line = strings.TrimPrefix(line, "/")
+		} else {
+			line = "**/" + line
+		}
+		if len(line) == 0 || !doublestar.ValidatePattern(line) {
+			core.Logger().Warn("ignoring invalid pattern in "+IgnoreFileName, "path", ignoreFilePath, "pattern", scanner.Text())
+			continue
+		}
+		rule.pattern = line
+		rules = append(rules, rule)
+	}
+	return rules, scanner.Err()
+}
@@ -0,0 +1,105 @@
+/*
+Copyright © 2023 Mark Johnson
+*/
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadIgnoreFile(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want ignoreRule
+	}{
+		{"any depth", "*.log", ignoreRule{dirPath: "sub", pattern: "**/*.log"}},
+		{"anchored", "/top.md", ignoreRule{dirPath: "sub", pattern: "top.md"}},
+		{"relative to the directory", "docs/*.md", ignoreRule{dirPath: "sub", pattern: "docs/*.md"}},
+		{"directory only", "build/", ignoreRule{dirPath: "sub", pattern: "**/build", dirOnly: true}},
+		{"negated", "!keep.log", ignoreRule{dirPath: "sub", pattern: "**/keep.log", negate: true}},
+		{"escaped", "\\!bang.md", ignoreRule{dirPath: "sub", pattern: "**/!bang.md"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ignoreFilePath := filepath.Join(t.TempDir(), IgnoreFileName)
+			writeFile(t, ignoreFilePath, "# comment\n\n"+test.line+"\n")
+			rules, err := readIgnoreFile(ignoreFilePath, "sub")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := []ignoreRule{test.want}; !reflect.DeepEqual(rules, want) {
+				t.Errorf("readIgnoreFile(%q) = %+v, want %+v", test.line, rules, want)
+			}
+		})
+	}
+}
+
+func TestFilterExcludes(t *testing.T) {
+	ignoreFiles := map[string]string{
+		IgnoreFileName:                       "*.log\n!keep.log\nbuild/\n/top.md\nsub/only.md\n",
+		"sub/" + IgnoreFileName:              "local.md\n",
+		"logs/" + IgnoreFileName:             "!*.log\n",
+		"logs/nested/deep/" + IgnoreFileName: "",
+	}
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		path    string
+		isDir   bool
+		want    bool
+	}{
+		{"not ignored", nil, nil, "notes.md", false, false},
+		{"ignore file", nil, nil, "sub/" + IgnoreFileName, false, true},
+		{"any depth", nil, nil, "a.log", false, true},
+		{"any depth below", nil, nil, "sub/a.log", false, true},
+		{"negated", nil, nil, "keep.log", false, false},
+		{"negated below", nil, nil, "sub/keep.log", false, false},
+		{"negated by a nested ignore file", nil, nil, "logs/a.log", false, false},
+		{"negated further below", nil, nil, "logs/nested/deep/a.log", false, false},
+		{"directory only", nil, nil, "build", true, true},
+		{"directory only below", nil, nil, "sub/build", true, true},
+		{"directory only file", nil, nil, "build", false, false},
+		{"anchored", nil, nil, "top.md", false, true},
+		{"anchored below", nil, nil, "sub/top.md", false, false},
+		{"relative to the root", nil, nil, "sub/only.md", false, true},
+		{"relative to the root below", nil, nil, "other/sub/only.md", false, false},
+		{"nested ignore file", nil, nil, "sub/local.md", false, true},
+		{"nested ignore file above", nil, nil, "local.md", false, false},
+		{"included", []string{"**/*.md"}, nil, "sub/notes.md", false, false},
+		{"not included", []string{"**/*.md"}, nil, "notes.txt", false, true},
+		{"include ignores directories", []string{"**/*.md"}, nil, "sub", true, false},
+		{"include relative to the root", []string{"*.md"}, nil, "sub/notes.md", false, true},
+		{"excluded directory", nil, []string{"**/drafts"}, "sub/drafts", true, true},
+		{"excluded file", nil, []string{"drafts/*.md"}, "drafts/notes.md", false, true},
+		{"excluded over included", []string{"**/*.md"}, []string{"drafts/**"}, "drafts/notes.md", false, true},
+		{"ignored over included", []string{"**/*.md"}, nil, "top.md", false, true},
+	}
+	sourceDirPath := t.TempDir()
+	for ignoreFilePath, contents := range ignoreFiles {
+		writeFile(t, filepath.Join(sourceDirPath, filepath.FromSlash(ignoreFilePath)), contents)
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filter := &filter{sourceDirPath, test.include, test.exclude, map[string][]ignoreRule{}}
+			filePath := filepath.Join(sourceDirPath, filepath.FromSlash(test.path))
+			if got := filter.excludes(filePath, test.isDir); got != test.want {
+				t.Errorf("excludes(%q, %t) = %t, want %t", test.path, test.isDir, got, test.want)
+			}
+		})
+	}
+}
+
+func writeFile(t *testing.T, filePath string, contents string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filePath, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,26 @@
+/*
+Copyright © 2023 Mark Johnson
+*/
+package file
+
+import (
+	"github.com/shelterbelt/majic-cli/majic/helpers/core"
+	"github.com/spf13/pflag"
+)
+
+// AddFlags adds the flags that control how ProcessPath
+// processes files to a command's flags.
+func AddFlags(flags *pflag.FlagSet) {
+	flags.StringSlice(core.FlagKeyInclude, nil, "only process files matching a glob pattern, e.g. '**/*.md', relative to the directory being processed")
+	flags.StringSlice(core.FlagKeyExclude, nil, "leave out files and directories matching a glob pattern, e.g. '**/node_modules'")
+	flags.Bool(core.FlagKeyDryRun, false, "run the processor without writing any files, showing a diff of each file it would change")
+	flags.Bool(core.FlagKeyDiff, false, "show a diff of each file processed against its result")
+	flags.String(core.FlagKeyInPlace, "", "replace each file with its processed result, keeping a backup when given a suffix, e.g. --in-place=.bak")
+	flags.Lookup(core.FlagKeyInPlace).NoOptDefVal = "true"
+	flags.Bool(core.FlagKeyPreserveMtime, false, "keep the modification time of files replaced in place")
+	flags.String(core.FlagKeyOutputLayout, "", "mirror the input subdirectories in the output directory, or flat")
+	flags.IntP(core.FlagKeyJobs, "j", 1, "number of files to process at the same time, 0 for one per CPU")
+	flags.String(core.FlagKeyOnError, "", "what to do when a file can't be processed: fail-fast or continue")
+	flags.Int(core.FlagKeyMaxErrors, 0, "stop once this many files have failed, implies --on-error continue")
+	flags.String(core.FlagKeyErrorReport, "", "write a JSON report of the files that failed to a file")
+}
@@ -0,0 +1,170 @@
+/*
+Copyright © 2023 Mark Johnson
+*/
+package file
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/shelterbelt/majic-cli/majic/helpers/core"
+)
+
+// inPlace replaces the files processed with their results, as
+// selected by the in_place and preserve_mtime keys.
+type inPlace struct {
+	backupSuffix  string
+	preserveMtime bool
+	// claimed holds the real paths of the files processed so
+	// far, so that a file reached through several links is
+	// only replaced once.
+	claimed map[string]bool
+}
+
+var currentInPlace *inPlace
+
+// preservedModeBits are the bits of a file's mode that are
+// copied to the file replacing it.
+const preservedModeBits = os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky
+
+func startInPlace() (*inPlace, error) {
+	value, err := core.Config().GetString(core.ConfigKeyInPlace)
+	if err != nil {
+		return nil, err
+	}
+	enabled, backupSuffix, err := core.ParseInPlace(value)
+	if err != nil {
+		return nil, &core.ConfigError{Err: err}
+	}
+	if !enabled {
+		return nil, nil
+	}
+	preserveMtime, err := core.Config().GetBool(core.ConfigKeyPreserveMtime)
+	if err != nil {
+		return nil, err
+	}
+	return &inPlace{backupSuffix, preserveMtime, map[string]bool{}}, nil
+}
+
+// checkOutputDir refuses to process files in place inside the
+// output directory, which "majic clean" deletes along with any
+// backups, or a directory containing the output directory,
+// whose generated files would be replaced too.
+func checkOutputDir(inputPath string, inputIsDir bool, outputDirPath string) error {
+	if isWithin(inputPath, outputDirPath) {
+		return &core.UsageError{Err: fmt.Errorf("%s is inside the output directory %s, set another %s to process it in place", inputPath, outputDirPath, core.ConfigKeyOutputDir)}
+	}
+	if inputIsDir && isWithin(outputDirPath, inputPath) {
+		return &core.UsageError{Err: fmt.Errorf("output directory %s is inside %s, set another %s to process it in place", outputDirPath, inputPath, core.ConfigKeyOutputDir)}
+	}
+	return nil
+}
+
+// isWithin reports whether path is dirPath or one of its
+// descendants, once symbolic links are resolved.
+func isWithin(path string, dirPath string) bool {
+	relativePath, err := filepath.Rel(realPath(dirPath), realPath(path))
+	return err == nil && relativePath != ".." && !strings.HasPrefix(relativePath, ".."+string(filepath.Separator))
+}
+
+// realPath returns the absolute path of filePath with symbolic
+// links resolved, as far as filePath exists.
+func realPath(filePath string) string {
+	absolutePath, err := filepath.Abs(filePath)
+	if err != nil {
+		return filePath
+	}
+	if resolvedPath, err := filepath.EvalSymlinks(absolutePath); err == nil {
+		return resolvedPath
+	}
+	parentPath := filepath.Dir(absolutePath)
+	if parentPath == absolutePath {
+		return absolutePath
+	}
+	return filepath.Join(realPath(parentPath), filepath.Base(absolutePath))
+}
+
+// claim reports whether filePath should be processed, which
+// it isn't in place when it links to a file already processed.
+func (inPlace *inPlace) claim(filePath string) bool {
+	if inPlace == nil {
+		return true
+	}
+	targetFilePath := realPath(filePath)
+	if inPlace.claimed[targetFilePath] {
+		return false
+	}
+	inPlace.claimed[targetFilePath] = true
+	return true
+}
+
+// linkTarget returns the file that filePath links to, or
+// filePath itself if it isn't a symbolic link.
+func linkTarget(filePath string) string {
+	if info, err := os.Lstat(filePath); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		if targetFilePath, err := filepath.EvalSymlinks(filePath); err == nil {
+			return targetFilePath
+		}
+	}
+	return filePath
+}
+
+// tempDirPath returns the directory that the result of filePath
+// is written to before it replaces the file.  This is the
+// directory holding the file, so the replacement is atomic.
+func (inPlace *inPlace) tempDirPath(filePath string) string {
+	return filepath.Dir(linkTarget(filePath))
+}
+
+// replace moves the processed result in tempFilePath over
+// filePath, or over the file it links to, keeping the file's
+// permissions and, if configured, its modification time and a
+// backup of the original.  It returns the path of the backup.
+func (inPlace *inPlace) replace(filePath string, tempFilePath string) (string, error) {
+	targetFilePath := linkTarget(filePath)
+	info, err := os.Stat(targetFilePath)
+	if err != nil {
+		return "", core.PathError(filePath, err)
+	}
+	if err := os.Chmod(tempFilePath, info.Mode()&preservedModeBits); err != nil {
+		return "", core.PathError(tempFilePath, err)
+	}
+	if inPlace.preserveMtime {
+		if err := os.Chtimes(tempFilePath, time.Time{}, info.ModTime()); err != nil {
+			return "", core.PathError(tempFilePath, err)
+		}
+	}
+	backupFilePath := ""
+	if len(inPlace.backupSuffix) > 0 {
+		backupFilePath = targetFilePath + inPlace.backupSuffix
+		if err := backupFile(targetFilePath, backupFilePath, info); err != nil {
+			return "", core.PathError(backupFilePath, err)
+		}
+	}
+	if err := os.Rename(tempFilePath, targetFilePath); err != nil {
+		return "", core.PathError(targetFilePath, err)
+	}
+	return backupFilePath, nil
+}
+
+// backupFile replaces backupFilePath with a hard link to
+// filePath, or a copy where links aren't supported.
+func backupFile(filePath string, backupFilePath string, info os.FileInfo) error {
+	if err := os.Remove(backupFilePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := os.Link(filePath, backupFilePath); err == nil {
+		return nil
+	}
+	if err := copyFile(filePath, backupFilePath, 32*1024); err != nil {
+		return err
+	}
+	if err := os.Chmod(backupFilePath, info.Mode()&preservedModeBits); err != nil {
+		return err
+	}
+	return os.Chtimes(backupFilePath, time.Time{}, info.ModTime())
+}
@@ -0,0 +1,208 @@
+/*
+Copyright © 2023 Mark Johnson
+*/
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/shelterbelt/majic-cli/majic/helpers/core"
+)
+
+// CloneableFileProcessor is an optional interface for file
+// processors that can process several files at the same time.
+// Clone returns a new processor, initialized like the original,
+// for each of the --jobs workers.
+type CloneableFileProcessor interface {
+	FileProcessor
+	Clone() FileProcessor
+}
+
+// fileTask is a file, or a failure to read a directory, found
+// while processing a directory with several jobs.
+type fileTask struct {
+	path           string
+	process        bool
+	result         FileResult
+	targetFileName string
+	lines          []string
+	err            error
+	done           chan struct{}
+}
+
+func configuredJobs() (int, error) {
+	jobs, err := core.Config().GetInt(core.ConfigKeyJobs)
+	if err != nil {
+		return 0, err
+	}
+	if jobs == 0 {
+		jobs = runtime.NumCPU()
+	}
+	return jobs, nil
+}
+
+// processDirectoryConcurrently processes the files found in
+// sourceDirPath with a pool of workers, each with its own clone
+// of processor.  Workers write to temporary files which are
+// renamed to their target file names, or over the files
+// processed in place, and reported, in the
+// order that ProcessDirectory would process the files, so the
+// results and output file names are the same as with one job.
+func processDirectoryConcurrently(sourceDirPath string, outputDirPath string, processor CloneableFileProcessor, jobs int) error {
+	tasks, err := collectFileTasks(sourceDirPath, processor)
+	if err != nil {
+		return err
+	}
+	logLines := core.Logger().Enabled(context.Background(), slog.LevelDebug)
+
+	queue := make(chan *fileTask)
+	stop := make(chan struct{})
+	var workers sync.WaitGroup
+	for i := 0; i < jobs; i++ {
+		workers.Add(1)
+		go func(processor FileProcessor) {
+			defer workers.Done()
+			for task := range queue {
+				task.result, task.err = processFile(task.path, processor, func(targetFileName string) (*os.File, error) {
+					task.targetFileName = targetFileName
+					if currentPreview.isDryRun() {
+						return createTempFile(os.TempDir())
+					} else if currentInPlace != nil {
+						return createTempFile(currentInPlace.tempDirPath(task.path))
+					}
+					return createTempFile(outputDirPath)
+				}, func(line string) {
+					if logLines {
+						task.lines = append(task.lines, line)
+					}
+				})
+				close(task.done)
+			}
+		}(processor.Clone())
+	}
+	go func() {
+		defer close(queue)
+		for _, task := range tasks {
+			if !task.process {
+				continue
+			}
+			select {
+			case queue <- task:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	for i, task := range tasks {
+		if err = commitFileTask(task, outputDirPath); err != nil {
+			close(stop)
+			workers.Wait()
+			for _, remaining := range tasks[i+1:] {
+				discardFileTask(remaining)
+			}
+			return err
+		}
+	}
+	workers.Wait()
+	return nil
+}
+
+// collectFileTasks lists the files in sourceDirPath and its
+// subdirectories in the order that ProcessDirectory visits
+// them, along with the files that are skipped or have gone
+// missing and the subdirectories that can't be read.
+func collectFileTasks(sourceDirPath string, processor FileProcessor) ([]*fileTask, error) {
+	files, err := readDirNames(sourceDirPath)
+	if err != nil {
+		return nil, err
+	}
+	var tasks []*fileTask
+	for _, name := range files {
+		sourceFilePath := filepath.Join(sourceDirPath, name)
+		info, err := os.Stat(sourceFilePath)
+		if errors.Is(err, os.ErrNotExist) {
+			tasks = append(tasks, &fileTask{path: sourceFilePath, result: FileResult{Status: FileStatusMissing, InputPath: sourceFilePath}})
+		} else if err != nil {
+			tasks = append(tasks, &fileTask{path: sourceFilePath, err: core.PathError(sourceFilePath, err)})
+		} else if currentFilter.excludes(sourceFilePath, info.IsDir()) {
+			core.Output().VerboseOutput("Excluding: " + sourceFilePath)
+		} else if info.IsDir() {
+			subdirTasks, err := collectFileTasks(sourceFilePath, processor)
+			if err != nil {
+				tasks = append(tasks, &fileTask{path: sourceFilePath, err: err})
+			}
+			tasks = append(tasks, subdirTasks...)
+		} else if processor.ShouldProcessFile(name) && currentInPlace.claim(sourceFilePath) {
+			tasks = append(tasks, &fileTask{path: sourceFilePath, process: true, done: make(chan struct{})})
+		} else {
+			tasks = append(tasks, &fileTask{path: sourceFilePath, result: FileResult{Status: FileStatusSkipped, InputPath: sourceFilePath}})
+		}
+	}
+	return tasks, nil
+}
+
+var tempFileCount atomic.Int64
+
+// createTempFile creates a file in dirPath to hold the output
+// of a file until it is committed.  Unlike os.CreateTemp the
+// file is created with the same permissions as CreateTargetFile
+// would give it.
+func createTempFile(dirPath string) (*os.File, error) {
+	for {
+		tempFilePath := filepath.Join(dirPath, fmt.Sprintf(".majic-%d-%d.tmp", os.Getpid(), tempFileCount.Add(1)))
+		file, err := os.OpenFile(tempFilePath, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0666)
+		if !errors.Is(err, os.ErrExist) {
+			return file, err
+		}
+	}
+}
+
+// commitFileTask waits for task to be processed, moves its
+// output to the target file and reports the result.  It returns
+// an error when processing should stop.
+func commitFileTask(task *fileTask, outputDirPath string) error {
+	core.Output().DetailedOutput(filepath.Base(task.path))
+	if task.process {
+		<-task.done
+		for _, line := range task.lines {
+			core.Output().VerboseOutput(line)
+		}
+		if task.err == nil {
+			task.err = processorError(task.path, deliverResult(&task.result, task.path, outputDirPath, task.targetFileName, true))
+		}
+	}
+	if task.err != nil {
+		discardFileTask(task)
+		return currentSummary.fail(task.path, task.err)
+	}
+	emitResult(task.result)
+	if task.process {
+		currentProgress.fileDone(task.result.BytesRead)
+	}
+	return nil
+}
+
+// discardFileTask removes the temporary output of a task that
+// won't be committed.
+func discardFileTask(task *fileTask) {
+	if !task.process {
+		return
+	}
+	select {
+	case <-task.done:
+		if len(task.result.OutputPath) > 0 {
+			os.Remove(task.result.OutputPath)
+		}
+	default:
+		// Never processed.
+	}
+}
@@ -0,0 +1,40 @@
+/*
+Copyright © 2023 Mark Johnson
+*/
+package file
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shelterbelt/majic-cli/majic/helpers/core"
+)
+
+func TestProcessPathOutputFailure(t *testing.T) {
+	for _, jobs := range []string{"1", "3"} {
+		t.Run("jobs "+jobs, func(t *testing.T) {
+			configureSummary(t, "", "")
+			sourceDirPath := t.TempDir()
+			writeFile(t, filepath.Join(sourceDirPath, "a", "notes.md"), "a\n")
+			writeFile(t, filepath.Join(sourceDirPath, "b", "notes.md"), "b\n")
+			// The subdirectory for a/notes.md can't be created.
+			outputDirPath := t.TempDir()
+			writeFile(t, filepath.Join(outputDirPath, "a"), "")
+			t.Setenv(core.EnvKey(core.ConfigKeyOutputDir), outputDirPath)
+			t.Setenv(core.EnvKey(core.ConfigKeyOutputLayout), "mirror")
+			t.Setenv(core.EnvKey(core.ConfigKeyJobs), jobs)
+
+			err := ProcessPath(sourceDirPath, &copyProcessor{})
+			if err == nil {
+				t.Fatal("ProcessPath() succeeded, want an error")
+			}
+			if !strings.Contains(err.Error(), "could not process "+filepath.Join(sourceDirPath, "a", "notes.md")) {
+				t.Errorf("ProcessPath() = %q, want could not process a/notes.md", err)
+			}
+			if got := core.ExitCode(err); got != core.ExitCodeProcessing {
+				t.Errorf("ExitCode() = %d, want %d", got, core.ExitCodeProcessing)
+			}
+		})
+	}
+}
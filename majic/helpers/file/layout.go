@@ -0,0 +1,90 @@
+/*
+Copyright © 2023 Mark Johnson
+*/
+package file
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/shelterbelt/majic-cli/majic/helpers/core"
+)
+
+// RelativePathProcessor is an optional interface for file
+// processors that name their output after where a file is found.
+// SetRelativePath is called before each file is processed with
+// its path relative to the directory passed to ProcessPath.
+//
+// Implementing it opts a processor in to mirroring the input
+// directory structure in the output directory, even if it
+// ignores the path, unless the output_layout key says otherwise.
+type RelativePathProcessor interface {
+	FileProcessor
+	SetRelativePath(relativePath string)
+}
+
+// layout is the output layout of the directory being processed.
+type layout struct {
+	sourceDirPath string
+	mirror        bool
+}
+
+var currentLayout *layout
+
+func startLayout(sourceDirPath string, processor FileProcessor) (*layout, error) {
+	value, err := core.Config().GetString(core.ConfigKeyOutputLayout)
+	if err != nil {
+		return nil, err
+	}
+	// Processors opt in to mirroring by implementing
+	// RelativePathProcessor.
+	_, mirror := processor.(RelativePathProcessor)
+	if len(value) > 0 {
+		outputLayout, err := core.ParseOutputLayout(value)
+		if err != nil {
+			return nil, &core.ConfigError{Err: err}
+		}
+		mirror = outputLayout == core.OutputLayoutMirror
+	}
+	return &layout{sourceDirPath, mirror}, nil
+}
+
+// relativePath returns the path of filePath relative to the
+// directory being processed, or its name when a single file is
+// processed.
+func (layout *layout) relativePath(filePath string) string {
+	if layout != nil {
+		if relativePath, err := filepath.Rel(layout.sourceDirPath, filePath); err == nil {
+			return relativePath
+		}
+	}
+	return filepath.Base(filePath)
+}
+
+// targetDirPath returns the directory in the output directory
+// that the file written for filePath goes in.
+func (layout *layout) targetDirPath(outputDirPath string, filePath string) string {
+	if layout != nil && layout.mirror {
+		return filepath.Join(outputDirPath, filepath.Dir(layout.relativePath(filePath)))
+	}
+	return outputDirPath
+}
+
+// targetFilePath returns the path of the file written for
+// filePath in the output directory, creating the subdirectory
+// it goes in with the mirror layout.
+func (layout *layout) targetFilePath(outputDirPath string, filePath string, targetFileName string) (string, error) {
+	targetDirPath := layout.targetDirPath(outputDirPath, filePath)
+	if err := os.MkdirAll(targetDirPath, 0750); err != nil {
+		return "", core.PathError(targetDirPath, err)
+	}
+	return filepath.Join(targetDirPath, targetFileName), nil
+}
+
+// setRelativePath passes the relative path of filePath to
+// processor if it implements RelativePathProcessor.
+func (layout *layout) setRelativePath(processor FileProcessor, filePath string) {
+	if relative, found := processor.(RelativePathProcessor); found {
+		relative.SetRelativePath(layout.relativePath(filePath))
+	}
+}
@@ -0,0 +1,141 @@
+/*
+Copyright © 2023 Mark Johnson
+*/
+package file
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/shelterbelt/majic-cli/majic/helpers/core"
+)
+
+const (
+	FileChangeCreated   string = "created"
+	FileChangeChanged   string = "changed"
+	FileChangeIdentical string = "identical"
+)
+
+// preview shows what processing changes, as selected by the
+// dry_run and diff keys.  In a dry run processors write their
+// results to temporary files outside the input and output
+// directories, which are removed once they are compared.
+type preview struct {
+	dryRun bool
+	diff   bool
+	// claimed holds the output files a dry run would have
+	// created so far, which a real run would find existing.
+	claimed map[string]bool
+}
+
+var currentPreview *preview
+
+func startPreview() (*preview, error) {
+	dryRun, err := core.Config().GetBool(core.ConfigKeyDryRun)
+	if err != nil {
+		return nil, err
+	}
+	diff, err := core.Config().GetBool(core.ConfigKeyDiff)
+	if err != nil {
+		return nil, err
+	}
+	if !dryRun && !diff {
+		return nil, nil
+	}
+	return &preview{dryRun, diff, map[string]bool{}}, nil
+}
+
+// isDryRun reports whether nothing should be written.
+func (preview *preview) isDryRun() bool {
+	return preview != nil && preview.dryRun
+}
+
+// deliverResult puts the result of processing filePath, held
+// in result.OutputPath, where it belongs: over filePath in
+// place, or in the output directory under targetFileName when
+// moveResult is set, comparing it with filePath along the way.
+// In a dry run the result is only compared, and removed.
+func deliverResult(result *FileResult, filePath string, outputDirPath string, targetFileName string, moveResult bool) error {
+	if len(result.OutputPath) == 0 {
+		if currentPreview.isDryRun() {
+			result.DryRun = true
+			result.Change = FileChangeIdentical
+		}
+		return nil
+	}
+	resultFilePath := result.OutputPath
+	switch {
+	case currentPreview.isDryRun():
+		defer os.Remove(resultFilePath)
+		targetFilePath := filePath
+		if currentInPlace == nil {
+			targetFilePath = currentPreview.claim(filepath.Join(currentLayout.targetDirPath(outputDirPath, filePath), targetFileName))
+		}
+		currentPreview.review(result, filePath, resultFilePath, targetFilePath)
+		result.DryRun = true
+		result.OutputPath = targetFilePath
+		if currentInPlace == nil {
+			result.Change = FileChangeCreated
+		} else if len(result.Diff) > 0 {
+			result.Change = FileChangeChanged
+		} else {
+			result.Change = FileChangeIdentical
+		}
+	case currentInPlace != nil:
+		currentPreview.review(result, filePath, resultFilePath, filePath)
+		backupFilePath, err := currentInPlace.replace(filePath, resultFilePath)
+		if err != nil {
+			return err
+		}
+		result.OutputPath = filePath
+		result.BackupPath = backupFilePath
+	case moveResult:
+		targetFilePath, err := currentLayout.targetFilePath(outputDirPath, filePath, targetFileName)
+		if err != nil {
+			return err
+		}
+		targetFilePath = uniqueFilePath(targetFilePath)
+		if err = os.Rename(resultFilePath, targetFilePath); err != nil {
+			return core.PathError(targetFilePath, err)
+		}
+		result.OutputPath = targetFilePath
+		currentPreview.review(result, filePath, targetFilePath, targetFilePath)
+	default:
+		currentPreview.review(result, filePath, resultFilePath, resultFilePath)
+	}
+	return nil
+}
+
+// claim returns the file that a real run would write to in
+// place of targetFilePath, as uniqueFilePath does, counting the
+// files claimed earlier in the dry run as existing.
+func (preview *preview) claim(targetFilePath string) string {
+	info, _ := os.Stat(targetFilePath)
+	desiredFilePath := targetFilePath
+	for i := 0; info != nil || preview.claimed[targetFilePath]; i++ {
+		targetFilePath = convertStringToUniqueFileName(desiredFilePath, i)
+		info, _ = os.Stat(targetFilePath)
+	}
+	preview.claimed[targetFilePath] = true
+	return targetFilePath
+}
+
+// review sets result's diff from filePath to its processed
+// result in resultFilePath, which ends up at targetFilePath.
+// A file that can't be compared is still processed.
+func (preview *preview) review(result *FileResult, filePath string, resultFilePath string, targetFilePath string) {
+	if preview == nil {
+		return
+	}
+	from, err := os.ReadFile(filePath)
+	if err != nil {
+		core.Logger().Warn("could not compare file with its result", "path", filePath, "error", err)
+		return
+	}
+	to, err := os.ReadFile(resultFilePath)
+	if err != nil {
+		core.Logger().Warn("could not compare file with its result", "path", filePath, "error", err)
+		return
+	}
+	result.Diff = unifiedDiff(filePath, targetFilePath, string(from), string(to))
+}
@@ -0,0 +1,79 @@
+/*
+Copyright © 2023 Mark Johnson
+*/
+package file
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/shelterbelt/majic-cli/majic/helpers/core"
+	"github.com/spf13/pflag"
+)
+
+// copyProcessor copies every file it is given unchanged.
+type copyProcessor struct{}
+
+func (processor *copyProcessor) Initialize(flags *pflag.FlagSet)        {}
+func (processor *copyProcessor) ShouldProcessFile(fileName string) bool { return true }
+func (processor *copyProcessor) UseGeneratedFileNames() bool            { return false }
+func (processor *copyProcessor) PreprocessNewTargetFile(file *os.File)  {}
+func (processor *copyProcessor) TargetFileName() string                 { return "" }
+func (processor *copyProcessor) ProcessLine(input string) string        { return input + "\n" }
+func (processor *copyProcessor) Reset()                                 {}
+func (processor *copyProcessor) Clone() FileProcessor                   { return &copyProcessor{} }
+
+func TestDryRunTargetFiles(t *testing.T) {
+	tests := []struct {
+		name     string
+		layout   string
+		existing []string
+		want     []string
+	}{
+		{"flat", "flat", nil, []string{"notes.md", "notes 1.md", "other.md"}},
+		{"flat with existing files", "flat", []string{"notes.md", "notes 2.md"}, []string{"notes 1.md", "notes 3.md", "other.md"}},
+		{"mirror", "mirror", nil, []string{"a/notes.md", "b/notes.md", "b/other.md"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			configureSummary(t, "", "")
+			sourceDirPath := t.TempDir()
+			writeFile(t, filepath.Join(sourceDirPath, "a", "notes.md"), "a\n")
+			writeFile(t, filepath.Join(sourceDirPath, "b", "notes.md"), "b\n")
+			writeFile(t, filepath.Join(sourceDirPath, "b", "other.md"), "b\n")
+			outputDirPath := t.TempDir()
+			for _, name := range test.existing {
+				writeFile(t, filepath.Join(outputDirPath, name), "")
+			}
+			t.Setenv(core.EnvKey(core.ConfigKeyOutputDir), outputDirPath)
+			t.Setenv(core.EnvKey(core.ConfigKeyOutputLayout), test.layout)
+			t.Setenv(core.EnvKey(core.ConfigKeyDryRun), "true")
+			var stdout bytes.Buffer
+			core.SetOutputWriters(&stdout, io.Discard)
+
+			if err := ProcessPath(sourceDirPath, &copyProcessor{}); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, line := range strings.Split(stdout.String(), "\n") {
+				if targetFilePath, found := strings.CutPrefix(line, "Would create "); found {
+					targetFilePath, _, _ = strings.Cut(targetFilePath, " from ")
+					relativePath, _ := filepath.Rel(outputDirPath, targetFilePath)
+					got = append(got, filepath.ToSlash(relativePath))
+				}
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("dry run would create %q, want %q", got, test.want)
+			}
+			entries, _ := os.ReadDir(outputDirPath)
+			if len(entries) != len(test.existing) {
+				t.Errorf("dry run wrote %d files to the output directory", len(entries)-len(test.existing))
+			}
+		})
+	}
+}
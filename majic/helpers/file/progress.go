@@ -0,0 +1,166 @@
+/*
+Copyright © 2023 Mark Johnson
+*/
+package file
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/shelterbelt/majic-cli/majic/helpers/core"
+)
+
+// Progress on a terminal is redrawn in place at most every
+// progressRedrawInterval, and only once processing has taken
+// longer than progressDelay so that short runs stay quiet.
+// Otherwise a summary line is written every
+// progressSummaryInterval.
+const progressRedrawInterval time.Duration = 100 * time.Millisecond
+const progressDelay time.Duration = 500 * time.Millisecond
+const progressSummaryInterval time.Duration = 10 * time.Second
+
+// progress tracks the files processed by ProcessDirectory
+// against a pre-count of the eligible files.
+type progress struct {
+	mutex       sync.Mutex
+	writer      io.Writer
+	terminal    bool
+	totalFiles  int
+	totalBytes  int64
+	doneFiles   int
+	doneBytes   int64
+	started     time.Time
+	lastUpdate  time.Time
+	displayed   bool
+	shown       bool
+	sharesLine  bool
+	lastSummary time.Time
+}
+
+var currentProgress *progress
+
+func startProgress(sourceDirPath string, processor FileProcessor) *progress {
+	if !core.Output().ShowProgress() {
+		return nil
+	}
+	writer := core.Output().Stderr()
+	state := &progress{writer: writer, terminal: core.IsTerminal(writer), started: time.Now()}
+	// Results written to the same terminal would otherwise be
+	// appended to the status line.
+	state.sharesLine = state.terminal && core.IsTerminal(core.Output().Stdout())
+	state.lastSummary = state.started
+	state.count(sourceDirPath, processor)
+	return state
+}
+
+// count adds the files in dirPath and its subdirectories that
+// ProcessDirectory would process to the totals, following
+// symbolic links as it does.
+func (state *progress) count(dirPath string, processor FileProcessor) {
+	names, err := readDirNames(dirPath)
+	if err != nil {
+		return
+	}
+	for _, name := range names {
+		filePath := filepath.Join(dirPath, name)
+		info, err := os.Stat(filePath)
+		if err != nil || currentFilter.excludes(filePath, info.IsDir()) {
+			continue
+		}
+		if info.IsDir() {
+			state.count(filePath, processor)
+		} else if processor.ShouldProcessFile(name) {
+			state.totalFiles++
+			state.totalBytes += info.Size()
+		}
+	}
+}
+
+// fileDone records a processed file and updates the display.
+func (state *progress) fileDone(bytesRead int64) {
+	if state == nil {
+		return
+	}
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	state.doneFiles++
+	state.doneBytes += bytesRead
+	now := time.Now()
+	if state.terminal {
+		if now.Sub(state.started) >= progressDelay && (!state.shown || now.Sub(state.lastUpdate) >= progressRedrawInterval) {
+			fmt.Fprint(state.writer, "\r\033[K"+state.status(now))
+			state.displayed = true
+			state.shown = true
+			state.lastUpdate = now
+		}
+	} else if now.Sub(state.lastSummary) >= progressSummaryInterval {
+		fmt.Fprintln(state.writer, state.status(now))
+		state.displayed = true
+		state.lastSummary = now
+	}
+}
+
+// clear removes the status line from the terminal before a
+// result is written to it, to be redrawn by the next fileDone.
+func (state *progress) clear() {
+	if state == nil {
+		return
+	}
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	if state.shown && state.sharesLine {
+		fmt.Fprint(state.writer, "\r\033[K")
+		state.shown = false
+	}
+}
+
+// finish writes the final status if progress was displayed.
+func (state *progress) finish() {
+	if state == nil {
+		return
+	}
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	if !state.displayed {
+		return
+	}
+	if state.terminal {
+		fmt.Fprintln(state.writer, "\r\033[K"+state.status(time.Now()))
+	} else {
+		fmt.Fprintln(state.writer, state.status(time.Now()))
+	}
+}
+
+func (state *progress) status(now time.Time) string {
+	elapsed := now.Sub(state.started)
+	rate := float64(state.doneBytes) / elapsed.Seconds()
+	status := fmt.Sprintf("Progress: %d/%d files, %s/%s, %s/s", state.doneFiles, state.totalFiles, formatBytes(float64(state.doneBytes)), formatBytes(float64(state.totalBytes)), formatBytes(rate))
+	if state.doneFiles > 0 && state.doneFiles < state.totalFiles {
+		// Estimate from whichever of bytes or files is further
+		// along, as directories of empty files have no bytes.
+		fraction := float64(state.doneFiles) / float64(state.totalFiles)
+		if state.totalBytes > 0 {
+			fraction = max(fraction, float64(state.doneBytes)/float64(state.totalBytes))
+		}
+		remaining := time.Duration(float64(elapsed) * (1 - fraction) / fraction)
+		status += ", ETA " + remaining.Round(time.Second).String()
+	}
+	return status
+}
+
+func formatBytes(bytes float64) string {
+	units := []string{"B", "KB", "MB", "GB", "TB"}
+	unit := 0
+	for bytes >= 1024 && unit < len(units)-1 {
+		bytes /= 1024
+		unit++
+	}
+	if unit == 0 {
+		return fmt.Sprintf("%.0f %s", bytes, units[unit])
+	}
+	return fmt.Sprintf("%.1f %s", bytes, units[unit])
+}
@@ -0,0 +1,31 @@
+/*
+Copyright © 2023 Mark Johnson
+*/
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProgressCount(t *testing.T) {
+	sourceDirPath := t.TempDir()
+	writeFile(t, filepath.Join(sourceDirPath, IgnoreFileName), "*.log\n")
+	writeFile(t, filepath.Join(sourceDirPath, "a", "one.md"), "one\n")
+	writeFile(t, filepath.Join(sourceDirPath, "a", "skip.log"), "skip\n")
+	if err := os.Symlink(filepath.Join(sourceDirPath, "a"), filepath.Join(sourceDirPath, "linked")); err != nil {
+		t.Skip("symbolic links aren't supported:", err)
+	}
+	currentFilter = &filter{sourceDirPath, nil, nil, map[string][]ignoreRule{}}
+	t.Cleanup(func() {
+		currentFilter = nil
+	})
+
+	state := &progress{}
+	state.count(sourceDirPath, &copyProcessor{})
+	// a/one.md, and again through the linked directory.
+	if state.totalFiles != 2 || state.totalBytes != 8 {
+		t.Errorf("count() found %d files, %d bytes, want 2 files, 8 bytes", state.totalFiles, state.totalBytes)
+	}
+}
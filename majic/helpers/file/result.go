@@ -0,0 +1,74 @@
+/*
+Copyright © 2023 Mark Johnson
+*/
+package file
+
+import (
+	"fmt"
+	"io"
+)
+
+const (
+	FileStatusProcessed string = "processed"
+	FileStatusSkipped   string = "skipped"
+	FileStatusMissing   string = "missing"
+	FileStatusFailed    string = "failed"
+)
+
+// FileResult is emitted for each file encountered while
+// processing a path.
+type FileResult struct {
+	Status       string `json:"status" yaml:"status"`
+	InputPath    string `json:"input_path" yaml:"input_path"`
+	OutputPath   string `json:"output_path,omitempty" yaml:"output_path,omitempty"`
+	BackupPath   string `json:"backup_path,omitempty" yaml:"backup_path,omitempty"`
+	BytesRead    int64  `json:"bytes_read" yaml:"bytes_read"`
+	BytesWritten int64  `json:"bytes_written" yaml:"bytes_written"`
+	Error        string `json:"error,omitempty" yaml:"error,omitempty"`
+	DryRun       bool   `json:"dry_run,omitempty" yaml:"dry_run,omitempty"`
+	Change       string `json:"change,omitempty" yaml:"change,omitempty"`
+	Diff         string `json:"diff,omitempty" yaml:"diff,omitempty"`
+}
+
+func (result FileResult) String() string {
+	switch result.Status {
+	case FileStatusProcessed:
+		if result.DryRun {
+			switch result.Change {
+			case FileChangeCreated:
+				return fmt.Sprintf("Would create %s from %s", result.OutputPath, result.InputPath)
+			case FileChangeChanged:
+				return fmt.Sprintf("Would change %s", result.InputPath)
+			}
+			return fmt.Sprintf("Would leave %s identical", result.InputPath)
+		}
+		if len(result.OutputPath) == 0 {
+			return fmt.Sprintf("Processed %s (%d bytes read, no output)", result.InputPath, result.BytesRead)
+		}
+		if result.OutputPath == result.InputPath {
+			if len(result.BackupPath) > 0 {
+				return fmt.Sprintf("Processed %s in place, backup %s (%d bytes read, %d bytes written)", result.InputPath, result.BackupPath, result.BytesRead, result.BytesWritten)
+			}
+			return fmt.Sprintf("Processed %s in place (%d bytes read, %d bytes written)", result.InputPath, result.BytesRead, result.BytesWritten)
+		}
+		return fmt.Sprintf("Processed %s -> %s (%d bytes read, %d bytes written)", result.InputPath, result.OutputPath, result.BytesRead, result.BytesWritten)
+	case FileStatusSkipped:
+		return "Skipping: " + result.InputPath
+	case FileStatusMissing:
+		return "File " + result.InputPath + " does not exist."
+	case FileStatusFailed:
+		return "Failed: " + result.Error
+	}
+	return result.Status + ": " + result.InputPath
+}
+
+type countingReader struct {
+	reader io.Reader
+	count  int64
+}
+
+func (reader *countingReader) Read(buffer []byte) (int, error) {
+	n, err := reader.reader.Read(buffer)
+	reader.count += int64(n)
+	return n, err
+}
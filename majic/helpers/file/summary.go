@@ -0,0 +1,184 @@
+/*
+Copyright © 2023 Mark Johnson
+*/
+package file
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/shelterbelt/majic-cli/majic/helpers/core"
+)
+
+// FileFailure records a file that couldn't be processed.
+type FileFailure struct {
+	Path     string `json:"path" yaml:"path"`
+	Error    string `json:"error" yaml:"error"`
+	ExitCode int    `json:"exit_code" yaml:"exit_code"`
+}
+
+// DryRunSummary counts what a dry run would do to the files
+// processed.
+type DryRunSummary struct {
+	Created   int `json:"created" yaml:"created"`
+	Changed   int `json:"changed" yaml:"changed"`
+	Identical int `json:"identical" yaml:"identical"`
+}
+
+// ProcessingSummary is emitted once ProcessPath has worked
+// through a directory with the continue error policy.
+type ProcessingSummary struct {
+	Processed int            `json:"processed" yaml:"processed"`
+	Skipped   int            `json:"skipped" yaml:"skipped"`
+	Missing   int            `json:"missing" yaml:"missing"`
+	Failed    int            `json:"failed" yaml:"failed"`
+	Stopped   bool           `json:"stopped" yaml:"stopped"`
+	Failures  []FileFailure  `json:"failures" yaml:"failures"`
+	DryRun    *DryRunSummary `json:"dry_run,omitempty" yaml:"dry_run,omitempty"`
+
+	policy    core.ErrorPolicy
+	maxErrors int
+}
+
+func (summary *ProcessingSummary) String() string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "%d processed, %d skipped, %d missing, %d failed", summary.Processed, summary.Skipped, summary.Missing, summary.Failed)
+	if summary.Stopped {
+		fmt.Fprintf(&builder, " (stopped at %s = %d)", core.ConfigKeyMaxErrors, summary.maxErrors)
+	}
+	if dryRun := summary.DryRun; dryRun != nil {
+		fmt.Fprintf(&builder, "\nDry run: %d would be created, %d changed, %d left identical", dryRun.Created, dryRun.Changed, dryRun.Identical)
+	}
+	if len(summary.Failures) > 0 {
+		builder.WriteString("\n")
+		table := tabwriter.NewWriter(&builder, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(table, "FILE\tEXIT CODE\tERROR")
+		for _, failure := range summary.Failures {
+			fmt.Fprintf(table, "%s\t%d\t%s\n", failure.Path, failure.ExitCode, failure.Error)
+		}
+		table.Flush()
+	}
+	return strings.TrimSuffix(builder.String(), "\n")
+}
+
+var currentSummary *ProcessingSummary
+
+// errStopped is returned up the directory tree once max_errors
+// files have failed.
+var errStopped = errors.New("too many files failed")
+
+func startSummary() (*ProcessingSummary, error) {
+	value, err := core.Config().GetString(core.ConfigKeyOnError)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := core.ParseErrorPolicy(value)
+	if err != nil {
+		return nil, &core.ConfigError{Err: err}
+	}
+	maxErrors, err := core.Config().GetInt(core.ConfigKeyMaxErrors)
+	if err != nil {
+		return nil, err
+	}
+	if maxErrors > 0 {
+		policy = core.ErrorPolicyContinue
+	}
+	summary := &ProcessingSummary{Failures: []FileFailure{}, policy: policy, maxErrors: maxErrors}
+	if currentPreview.isDryRun() {
+		summary.DryRun = &DryRunSummary{}
+	}
+	return summary, nil
+}
+
+// emitResult emits result and counts it in the summary.
+func emitResult(result FileResult) {
+	if summary := currentSummary; summary != nil {
+		switch result.Status {
+		case FileStatusProcessed:
+			summary.Processed++
+		case FileStatusSkipped:
+			summary.Skipped++
+		case FileStatusMissing:
+			summary.Missing++
+		}
+		if dryRun := summary.DryRun; dryRun != nil && result.DryRun {
+			switch result.Change {
+			case FileChangeCreated:
+				dryRun.Created++
+			case FileChangeChanged:
+				dryRun.Changed++
+			case FileChangeIdentical:
+				dryRun.Identical++
+			}
+		}
+	}
+	currentProgress.clear()
+	core.Output().Emit(result)
+	if len(result.Diff) > 0 && !core.Output().Structured() {
+		printDiff(result.Diff)
+	}
+}
+
+// fail records a file that couldn't be processed and returns
+// nil when processing should continue with the next file, or
+// err when it should stop.  Errors that stopped processing are
+// returned unchanged by the callers further up the directory
+// tree.
+func (summary *ProcessingSummary) fail(path string, err error) error {
+	if summary == nil || summary.Stopped || summary.policy == core.ErrorPolicyFailFast {
+		return err
+	}
+	summary.Failed++
+	summary.Failures = append(summary.Failures, FileFailure{path, err.Error(), core.ExitCode(err)})
+	emitResult(FileResult{Status: FileStatusFailed, InputPath: path, Error: err.Error()})
+	if summary.maxErrors > 0 && summary.Failed >= summary.maxErrors {
+		summary.Stopped = true
+		return errStopped
+	}
+	return nil
+}
+
+// finish emits the summary and writes the error report when
+// processing continues past failures, and returns an error if
+// any file failed.  The summary of a dry run is always emitted.
+func (summary *ProcessingSummary) finish(sourceDirPath string, err error) error {
+	if summary.policy == core.ErrorPolicyFailFast {
+		if summary.DryRun != nil && err == nil {
+			core.Output().Emit(summary)
+		}
+		return err
+	}
+	core.Output().Emit(summary)
+	reportPath, reportErr := core.Config().GetPath(core.ConfigKeyErrorReport)
+	if reportErr == nil && len(reportPath) > 0 {
+		reportErr = writeErrorReport(reportPath, summary)
+	}
+	if reportErr != nil {
+		core.Logger().Warn("could not write error report", "error", reportErr)
+	}
+	if errors.Is(err, errStopped) && summary.Failed == 1 {
+		return &core.ProcessorError{Path: sourceDirPath, Err: errors.New("stopped after 1 failure")}
+	} else if errors.Is(err, errStopped) {
+		return &core.ProcessorError{Path: sourceDirPath, Err: fmt.Errorf("stopped after %d failures", summary.Failed)}
+	} else if err != nil {
+		return err
+	}
+	if summary.Failed == 1 {
+		return &core.ProcessorError{Path: sourceDirPath, Err: errors.New("1 file failed")}
+	} else if summary.Failed > 0 {
+		return &core.ProcessorError{Path: sourceDirPath, Err: fmt.Errorf("%d files failed", summary.Failed)}
+	}
+	return nil
+}
+
+func writeErrorReport(path string, summary *ProcessingSummary) error {
+	contents, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(contents, '\n'), 0644)
+}
@@ -0,0 +1,140 @@
+/*
+Copyright © 2023 Mark Johnson
+*/
+package file
+
+import (
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/shelterbelt/majic-cli/majic/helpers/core"
+)
+
+// configureSummary sets the on_error and max_errors keys, left
+// to their defaults when empty, and discards the results
+// emitted while the test runs.
+func configureSummary(t *testing.T, onError string, maxErrors string) {
+	t.Helper()
+	t.Setenv("MAJIC_HOME", t.TempDir())
+	t.Setenv(core.EnvKey(core.ConfigKeyNoProjectConfig), "true")
+	for key, value := range map[string]string{core.ConfigKeyOnError: onError, core.ConfigKeyMaxErrors: maxErrors} {
+		// t.Setenv restores the environment once the test is done.
+		t.Setenv(core.EnvKey(key), value)
+		if len(value) == 0 {
+			os.Unsetenv(core.EnvKey(key))
+		}
+	}
+	core.SetOutputWriters(io.Discard, io.Discard)
+	t.Cleanup(func() {
+		core.SetOutputWriters(nil, nil)
+	})
+}
+
+func TestStartSummary(t *testing.T) {
+	tests := []struct {
+		name          string
+		onError       string
+		maxErrors     string
+		wantPolicy    core.ErrorPolicy
+		wantMaxErrors int
+		wantErr       bool
+	}{
+		{"default", "", "", core.ErrorPolicyFailFast, 0, false},
+		{"continue", "continue", "", core.ErrorPolicyContinue, 0, false},
+		{"fail fast", "Fail-Fast", "", core.ErrorPolicyFailFast, 0, false},
+		{"max errors implies continue", "", "3", core.ErrorPolicyContinue, 3, false},
+		{"max errors overrides fail fast", "fail-fast", "2", core.ErrorPolicyContinue, 2, false},
+		{"unsupported policy", "retry", "", "", 0, true},
+		{"invalid max errors", "", "many", "", 0, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			configureSummary(t, test.onError, test.maxErrors)
+			summary, err := startSummary()
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("startSummary() = %+v, want an error", summary)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if summary.policy != test.wantPolicy || summary.maxErrors != test.wantMaxErrors {
+				t.Errorf("startSummary() policy %q, max errors %d, want %q, %d", summary.policy, summary.maxErrors, test.wantPolicy, test.wantMaxErrors)
+			}
+		})
+	}
+}
+
+func TestSummaryFail(t *testing.T) {
+	fileErr := errors.New("line too long")
+	tests := []struct {
+		name        string
+		onError     string
+		maxErrors   string
+		failures    int
+		wantErrs    []error
+		wantFailed  int
+		wantStopped bool
+	}{
+		{"fail fast", "fail-fast", "", 1, []error{fileErr}, 0, false},
+		{"continue", "continue", "", 3, []error{nil, nil, nil}, 3, false},
+		{"below max errors", "", "3", 2, []error{nil, nil}, 2, false},
+		{"max errors", "", "2", 2, []error{nil, errStopped}, 2, true},
+		{"after max errors", "", "1", 2, []error{errStopped, fileErr}, 1, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			configureSummary(t, test.onError, test.maxErrors)
+			summary, err := startSummary()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range test.failures {
+				if err := summary.fail("file.md", fileErr); err != test.wantErrs[i] {
+					t.Errorf("failure %d: fail() = %v, want %v", i+1, err, test.wantErrs[i])
+				}
+			}
+			if summary.Failed != test.wantFailed || len(summary.Failures) != test.wantFailed || summary.Stopped != test.wantStopped {
+				t.Errorf("summary failed %d (%d failures), stopped %t, want %d, %t", summary.Failed, len(summary.Failures), summary.Stopped, test.wantFailed, test.wantStopped)
+			}
+		})
+	}
+}
+
+func TestSummaryFinish(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int
+		err      error
+		want     string
+	}{
+		{"no failures", 0, nil, ""},
+		{"one failure", 1, nil, "could not process dir: 1 file failed"},
+		{"failures", 2, nil, "could not process dir: 2 files failed"},
+		{"stopped after one failure", 1, errStopped, "could not process dir: stopped after 1 failure"},
+		{"stopped", 2, errStopped, "could not process dir: stopped after 2 failures"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			configureSummary(t, "continue", "")
+			summary, err := startSummary()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for range test.failures {
+				summary.fail("file.md", errors.New("line too long"))
+			}
+			got := ""
+			if err := summary.finish("dir", test.err); err != nil {
+				got = err.Error()
+			}
+			if got != test.want {
+				t.Errorf("finish() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -5,6 +5,8 @@ package plugin
 
 import (
 	"errors"
+	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"plugin"
@@ -18,55 +20,167 @@ type MajicPlugin interface {
 	Register() []string
 }
 
-func LoadPlugins(root *cobra.Command) {
+// ConfigurablePlugin is an optional interface for plugins that
+// declare the configuration keys they use so that majic can
+// validate and document them.
+type ConfigurablePlugin interface {
+	ConfigSchema() core.ConfigSchema
+}
+
+// LoggingPlugin is an optional interface for plugins that log
+// diagnostics.  The logger tags each message with the plugin's
+// name, taken from the plugin file name.
+type LoggingPlugin interface {
+	SetLogger(logger *slog.Logger)
+}
+
+// LoadedPlugin records the outcome of loading a plugin file.
+type LoadedPlugin struct {
+	Path     string
+	Commands []string
+	// Conflicts describes the commands that weren't added
+	// because their name or an alias is already in use.
+	Conflicts []string
+	Err       error
+}
+
+var loadedPlugins []LoadedPlugin
+
+// LoadedPlugins returns the plugins found by LoadPlugins,
+// including those that couldn't be loaded.
+func LoadedPlugins() []LoadedPlugin {
+	return loadedPlugins
+}
+
+// LoadPlugins loads every plugin in the plugins directory and
+// adds their commands to root.  A plugin that can't be loaded
+// doesn't prevent loading the others; the returned error joins
+// a PluginLoadError for each of them.
+func LoadPlugins(root *cobra.Command) error {
 	appConfig := core.Config()
-	pluginsDirPath := appConfig.GetD(core.ConfigKeyPluginsDir, core.DefaultPluginsDir).(string)
+	pluginsDirPath, err := appConfig.GetPath(core.ConfigKeyPluginsDir)
+	if err != nil {
+		return err
+	}
 	pluginsDir, err := os.Open(pluginsDirPath)
-	if (err == nil) || (err != nil && !errors.Is(err, os.ErrNotExist)) {
-		core.HandleError(err)
-		defer pluginsDir.Close()
-		files, err := pluginsDir.Readdirnames(-1)
-		core.HandleError(err)
-		for i := 0; i < len(files); i++ {
-			pluginPath := filepath.Join(pluginsDirPath, files[i])
-			info, err := os.Stat(pluginPath)
-			if err != nil && !errors.Is(err, os.ErrExist) {
-				core.Output().NormalOutput("File " + pluginPath + " does not exist.")
-			} else {
-				core.HandleError(err)
-				if !info.IsDir() && strings.HasSuffix(files[i], ".so") {
-					plugin, err := plugin.Open(pluginPath)
-					core.HandleError(err)
-					instanceSym, err := plugin.Lookup("Plugin")
-					core.HandleError(err)
-					var instance MajicPlugin
-					instance, found := instanceSym.(MajicPlugin)
-					if found {
-						commands := instance.Register()
-						registerCommands(plugin, root, commands)
-					} else {
-						core.Output().NormalOutput("Failed to load plugin: " + pluginPath)
-					}
-
-				} else {
-					core.Output().VerboseOutput("Skipping non-plugin file: " + files[i])
-				}
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return core.PathError(pluginsDirPath, err)
+	}
+	defer pluginsDir.Close()
+	files, err := pluginsDir.Readdirnames(-1)
+	if err != nil {
+		return core.PathError(pluginsDirPath, err)
+	}
+	var loadErrors []error
+	for i := 0; i < len(files); i++ {
+		pluginPath := filepath.Join(pluginsDirPath, files[i])
+		info, err := os.Stat(pluginPath)
+		if err != nil {
+			core.Logger().Warn("file does not exist", "path", pluginPath)
+		} else if !info.IsDir() && strings.HasSuffix(files[i], ".so") {
+			loaded := LoadedPlugin{Path: pluginPath}
+			err = loadPlugin(root, &loaded)
+			if err != nil {
+				loaded.Err = &core.PluginLoadError{Path: pluginPath, Err: err}
+				loadErrors = append(loadErrors, loaded.Err)
 			}
+			loadedPlugins = append(loadedPlugins, loaded)
+		} else {
+			core.Logger().Debug("skipping non-plugin file", "path", pluginPath)
+		}
+	}
+	return errors.Join(loadErrors...)
+}
+
+func loadPlugin(root *cobra.Command, loaded *LoadedPlugin) error {
+	pluginPath := loaded.Path
+	plugin, err := plugin.Open(pluginPath)
+	if err != nil {
+		return err
+	}
+	instanceSym, err := plugin.Lookup("Plugin")
+	if err != nil {
+		return err
+	}
+	instance, found := instanceSym.(MajicPlugin)
+	if !found {
+		return fmt.Errorf("symbol Plugin is a %T, which doesn't implement MajicPlugin", instanceSym)
+	}
+	setPluginLogger(pluginPath, instance)
+	registerConfigSchema(pluginPath, instance)
+	commands := instance.Register()
+	return registerCommands(plugin, root, commands, loaded)
+}
+
+func setPluginLogger(pluginPath string, instance MajicPlugin) {
+	logging, found := instance.(LoggingPlugin)
+	if found {
+		logging.SetLogger(core.PluginLogger(strings.TrimSuffix(filepath.Base(pluginPath), ".so")))
+	}
+}
+
+func registerConfigSchema(pluginPath string, instance MajicPlugin) {
+	configurable, found := instance.(ConfigurablePlugin)
+	if found {
+		err := core.RegisterConfigSchema(configurable.ConfigSchema())
+		if err != nil {
+			core.Logger().Warn(err.Error(), "path", pluginPath)
 		}
 	}
 }
 
-func registerCommands(plugin *plugin.Plugin, root *cobra.Command, commands []string) {
+// registerCommands adds the named commands to root, recording
+// them in loaded.  Commands whose name or aliases are already
+// used by one of root's commands are skipped, as Cobra would
+// only ever run the first of them.
+func registerCommands(plugin *plugin.Plugin, root *cobra.Command, commands []string, loaded *LoadedPlugin) error {
+	var commandErrors []error
 	for j := 0; len(commands) > j; j++ {
 		cmdVarSym, err := plugin.Lookup(commands[j])
+		if err != nil {
+			commandErrors = append(commandErrors, err)
+			continue
+		}
+		command, found := cmdVarSym.(**cobra.Command)
+		if !found {
+			commandErrors = append(commandErrors, fmt.Errorf("%s is a %T, not a *cobra.Command", commands[j], cmdVarSym))
+			continue
+		}
+		if conflicts := commandConflicts(root, *command); len(conflicts) > 0 {
+			conflict := "command " + (*command).Name() + " not added, " + strings.Join(conflicts, ", ") + " already in use"
+			core.Logger().Warn(conflict, "path", loaded.Path)
+			loaded.Conflicts = append(loaded.Conflicts, conflict)
+			continue
+		}
+		initSym, err := plugin.Lookup("Init" + commands[j])
 		if err == nil {
-			initSym, err := plugin.Lookup("Init" + commands[j])
-			if err == nil {
-				initSym.(func())()
+			if initFunc, found := initSym.(func()); found {
+				initFunc()
 			}
-			root.AddCommand(*cmdVarSym.(**cobra.Command))
-		} else {
-			core.Output().NormalOutput("Warning: " + err.Error())
+		}
+		root.AddCommand(*command)
+		loaded.Commands = append(loaded.Commands, (*command).Name())
+	}
+	return errors.Join(commandErrors...)
+}
+
+// commandConflicts returns the name and aliases of command
+// that are used by root's existing commands.
+func commandConflicts(root *cobra.Command, command *cobra.Command) []string {
+	used := map[string]bool{}
+	for _, existing := range root.Commands() {
+		used[existing.Name()] = true
+		for _, alias := range existing.Aliases {
+			used[alias] = true
+		}
+	}
+	var conflicts []string
+	for _, name := range append([]string{command.Name()}, command.Aliases...) {
+		if used[name] {
+			conflicts = append(conflicts, name)
 		}
 	}
+	return conflicts
 }
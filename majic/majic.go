@@ -4,22 +4,54 @@ Copyright © 2023 Mark Johnson
 package main
 
 import (
+	"os"
+
 	"github.com/shelterbelt/majic-cli/majic/cmd"
 
 	"github.com/shelterbelt/majic-cli/majic/helpers/core"
-	"github.com/shelterbelt/majic-cli/majic/helpers/file"
 	"github.com/shelterbelt/majic-cli/majic/helpers/plugin"
 )
 
 func main() {
-	pluginsPath := core.Config().GetD(core.ConfigKeyPluginsDir, core.DefaultPluginsDir).(string)
-	inputDirPath := core.Config().GetD(core.ConfigKeyInputDir, core.DefaultInputDir).(string)
-	outputDirPath := file.CreateOutputDir()
-	core.Output().DetailedOutput("Plugins directory: " + pluginsPath)
-	core.Output().DetailedOutput("Input directory: " + inputDirPath)
-	core.Output().DetailedOutput("Output directory: " + outputDirPath)
+	core.SetStartupFlags(cmd.ParseEarlyFlags(os.Args[1:]))
+
+	// A configuration that can't be loaded is reported when the
+	// command runs, so that the commands used to repair it still
+	// work.
+	if core.LoadConfig() == nil {
+		logDirectory("Plugins directory: ", core.ConfigKeyPluginsDir)
+		logDirectory("Input directory: ", core.ConfigKeyInputDir)
+		logDirectory("Output directory: ", core.ConfigKeyOutputDir)
 
-	plugin.LoadPlugins(cmd.GetRootCommand())
+		// A plugin that can't be loaded is reported without
+		// preventing the rest of the application from working.
+		if err := plugin.LoadPlugins(cmd.GetRootCommand()); err != nil {
+			if joined, ok := err.(interface{ Unwrap() []error }); ok {
+				for _, pluginErr := range joined.Unwrap() {
+					core.ReportError(pluginErr)
+				}
+			} else {
+				core.ReportError(err)
+			}
+		}
+		// An out of date configuration file is migrated in memory,
+		// and only written by "majic config migrate".
+		if _, err := core.Config().ApplyPendingMigrations(); err != nil {
+			core.Logger().Warn(err.Error())
+		}
+		for _, problem := range core.Config().Validate() {
+			core.Logger().Warn(problem.Error())
+		}
+	}
 
 	cmd.Execute()
 }
+
+func logDirectory(label string, key string) {
+	path, err := core.Config().GetPath(key)
+	if err != nil {
+		core.Logger().Warn(err.Error())
+		return
+	}
+	core.Output().DetailedOutput(label + path)
+}